@@ -0,0 +1,28 @@
+// Package bcltest parses bcl blocks in tests of resources
+package bcltest
+
+import (
+	"testing"
+
+	"github.com/bluebookrun/bluebook/bcl"
+)
+
+// Block parses text and returns its first block, the test fails if text
+// can't be parsed or doesn't start with a block
+func Block(t *testing.T, text string) *bcl.BlockNode {
+	t.Helper()
+
+	tree, err := bcl.Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(tree.Root.Nodes) == 0 {
+		t.Fatalf("no block in %q", text)
+	}
+	block, ok := tree.Root.Nodes[0].(*bcl.BlockNode)
+	if !ok {
+		t.Fatalf("%s is not a block", tree.Root.Nodes[0])
+	}
+	return block
+}
@@ -203,6 +203,12 @@ func lexString(l *lexer) stateFn {
 			return l.errorf("unterminated string")
 		case isNewLine(c):
 			return l.errorf("string does not allow new lines")
+		case c == '$' && l.peek() == '{':
+			// template blocks may contain quoted strings,
+			// e.g. "${jsonpath(var.a, "$.b")}"
+			if !l.skipTemplate() {
+				return l.errorf("unterminated template block")
+			}
 		case c != '"':
 			// absorb anything that's not a double quote
 		default:
@@ -218,6 +224,25 @@ func lexString(l *lexer) stateFn {
 	return lexStart
 }
 
+// skipTemplate consumes template block up to the closing curly brace.
+// Curly braces within quoted strings do not close the block.
+func (l *lexer) skipTemplate() bool {
+	inQuote := false
+	for {
+		c := l.next()
+		switch {
+		case c == eof || isNewLine(c):
+			return false
+		case inQuote && c == '\\':
+			l.next()
+		case c == '"':
+			inQuote = !inQuote
+		case c == '}' && !inQuote:
+			return true
+		}
+	}
+}
+
 func lexMultiString(l *lexer) stateFn {
 	if l.heredocTerminator == "" {
 		return l.errorf("missing heredoc terminator")
@@ -279,8 +304,8 @@ func lexSpace(l *lexer) stateFn {
 func lexIdentifier(l *lexer) stateFn {
 	for {
 		switch c := l.next(); {
-		case isAlphaNumeric(c):
-			// absorb
+		case isAlphaNumeric(c) || c == '.':
+			// absorb, dots are allowed in bare values, e.g. 1.5
 		default:
 			l.backup()
 			word := l.input[l.start:l.pos]
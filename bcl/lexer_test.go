@@ -115,6 +115,7 @@ func TestLexesIdentifiers(t *testing.T) {
 		"i1",
 		"1i",
 		"i_123",
+		"1.5",
 	}
 
 	for _, testValue := range testCases {
@@ -140,6 +141,9 @@ func TestLexesString(t *testing.T) {
 		`"123"`,
 		`"$var"`,
 		`" string with white space"`,
+		`"${var.a}"`,
+		`"${jsonpath(var.a, "$.b")}"`,
+		`"id ${jsonpath(var.a, "$['}']")} end"`,
 	}
 
 	for _, testValue := range testCases {
@@ -175,6 +179,12 @@ func TestLexesStringWithError(t *testing.T) {
 	if item.typ != itemError {
 		t.Errorf("expected error, got %v", item)
 	}
+
+	l = lex(`"${jsonpath(var.a, "$.b")"`)
+	item = <-l.items
+	if item.typ != itemError {
+		t.Errorf("expected error, got %v", item)
+	}
 }
 
 func TestLexesBlock(t *testing.T) {
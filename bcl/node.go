@@ -3,6 +3,7 @@ package bcl
 import (
 	"bytes"
 	"fmt"
+	"path/filepath"
 )
 
 type Node interface {
@@ -24,6 +25,7 @@ const (
 	NodeList                       // a list of nodes
 	NodeBlock                      // a block defining any object in test definition
 	NodeExpression                 // expression node, field = value
+	NodeMap                        // a map of key = value entries
 )
 
 type StringNode struct {
@@ -118,6 +120,13 @@ func (e *ExpressionNode) ValueAsList() (*ListNode, error) {
 	return nil, fmt.Errorf("unable to convert expression value to list: %s", e)
 }
 
+func (e *ExpressionNode) ValueAsMap() (*MapNode, error) {
+	if mapNode, ok := e.Value.(*MapNode); ok {
+		return mapNode, nil
+	}
+	return nil, fmt.Errorf("unable to convert expression value to map: %s", e)
+}
+
 func (t *Tree) newExpression(field *IdentifierNode, value Node) *ExpressionNode {
 	return &ExpressionNode{
 		NodeType: NodeExpression,
@@ -127,6 +136,32 @@ func (t *Tree) newExpression(field *IdentifierNode, value Node) *ExpressionNode
 	}
 }
 
+type MapNode struct {
+	NodeType
+	tree    *Tree
+	Entries []*ExpressionNode // entries in the order they are defined
+}
+
+func (m *MapNode) String() string {
+	b := new(bytes.Buffer)
+	fmt.Fprint(b, "{")
+	for _, e := range m.Entries {
+		fmt.Fprintf(b, "%s, ", e)
+	}
+	if b.Len() > 2 {
+		b.Truncate(b.Len() - 2)
+	}
+	fmt.Fprint(b, "}")
+	return b.String()
+}
+
+func (t *Tree) newMap() *MapNode {
+	return &MapNode{
+		NodeType: NodeMap,
+		tree:     t,
+	}
+}
+
 type BlockNode struct {
 	NodeType
 	tree        *Tree
@@ -134,11 +169,22 @@ type BlockNode struct {
 	Driver      *StringNode       // block driver
 	Name        *StringNode       // user provided block name for referencing later
 	Expressions []*ExpressionNode // list of expressions in the block
+	Blocks      []*BlockNode      // nested blocks, e.g. multipart { ... }
+	FileName    string            // file the block is defined in, empty for parsed text
+}
+
+// Path resolves relative file name against the directory of the file the
+// block is defined in, so configurations can be run from any directory
+func (b *BlockNode) Path(name string) string {
+	if b.FileName == "" || filepath.IsAbs(name) {
+		return name
+	}
+	return filepath.Join(filepath.Dir(b.FileName), name)
 }
 
 func (b *BlockNode) String() string {
-	return fmt.Sprintf("%s %s %s { %v }",
-		b.Id, b.Driver, b.Name, b.Expressions)
+	return fmt.Sprintf("%s %s %s { %v %v }",
+		b.Id, b.Driver, b.Name, b.Expressions, b.Blocks)
 }
 
 func (b *BlockNode) Ref() string {
@@ -154,5 +200,6 @@ func (t *Tree) newBlock(idNode *IdentifierNode, driverNode *StringNode, nameNode
 		Id:       idNode,
 		Driver:   driverNode,
 		Name:     nameNode,
+		FileName: t.FileName,
 	}
 }
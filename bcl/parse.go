@@ -7,6 +7,7 @@ import (
 
 type Tree struct {
 	Root        *ListNode // Root node of this tree
+	FileName    string    // name of the parsed file, empty for parsed text
 	lex         *lexer    // lexer used to tokenize input text
 	text        string    // input text that was passed into the parser
 	tokenBuffer [1]item   // token buffer for peeking and stepping back
@@ -134,7 +135,23 @@ func (t *Tree) parse() {
 func (t *Tree) parseBlock() *BlockNode {
 	// current item in the buffer is an identifier
 	identToken := t.expect(itemIdentifier)
-	driverToken := t.expect(itemString)
+	return t.parseBlockAfter(identToken)
+}
+
+// parses the rest of the block after its identifier
+func (t *Tree) parseBlockAfter(identToken item) *BlockNode {
+	driverToken := t.expectStringOrBlockStart()
+	if driverToken.typ == itemBlockStart {
+		// block without driver and name, e.g. locals { ... }
+		blockNode := t.newBlock(
+			t.newIdentifier(identToken.value),
+			t.newString(""),
+			t.newString(""),
+		)
+
+		t.parseBlockBody(blockNode)
+		return blockNode
+	}
 
 	token := t.expectStringOrBlockStart()
 	if token.typ == itemBlockStart {
@@ -144,8 +161,7 @@ func (t *Tree) parseBlock() *BlockNode {
 			t.newString(driverToken.value),
 		)
 
-		blockNode.Expressions = t.parseExpressions()
-		t.expect(itemBlockEnd)
+		t.parseBlockBody(blockNode)
 		return blockNode
 	} else {
 		blockNode := t.newBlock(
@@ -156,52 +172,59 @@ func (t *Tree) parseBlock() *BlockNode {
 
 		// consume curly brace
 		t.expect(itemBlockStart)
-		blockNode.Expressions = t.parseExpressions()
-		t.expect(itemBlockEnd)
+		t.parseBlockBody(blockNode)
 
 		return blockNode
 	}
 	return nil
 }
 
-func (t *Tree) parseExpressions() []*ExpressionNode {
-	expressions := make([]*ExpressionNode, 0)
+// parses expressions and nested blocks up to the closing curly brace,
+// expressions may be separated by commas
+func (t *Tree) parseBlockBody(blockNode *BlockNode) {
 	for {
 		token := t.nextNonSpaceOrComment()
-		if token.typ == itemBlockEnd {
-			t.backup()
-			break
-		} else {
+		switch token.typ {
+		case itemBlockEnd:
+			return
+		case itemComma:
+			// ignore
+		case itemIdentifier:
+			next := t.nextNonSpaceOrComment()
 			t.backup()
-		}
 
-		expression := t.parseExpression()
-		expressions = append(expressions, expression)
+			if next.typ == itemOperatorAssign {
+				blockNode.Expressions = append(blockNode.Expressions, t.parseExpression(token))
+			} else {
+				blockNode.Blocks = append(blockNode.Blocks, t.parseBlockAfter(token))
+			}
+		default:
+			t.errorf("unexpected token %v, expected identifier", token)
+		}
 	}
-	return expressions
 }
 
-// Parses single expression
-func (t *Tree) parseExpression() *ExpressionNode {
-	field := t.expect(itemIdentifier)
+// Parses single expression after its field
+func (t *Tree) parseExpression(field item) *ExpressionNode {
 	t.expect(itemOperatorAssign)
-	value := t.parseStringOrList()
+	value := t.parseValue()
 	return t.newExpression(t.newIdentifier(field.value), value)
 }
 
-func (t *Tree) parseStringOrList() (node Node) {
+// parses a string, a list or a map. Bare values, e.g. numbers
+// and booleans, are parsed as strings.
+func (t *Tree) parseValue() (node Node) {
 	token := t.nextNonSpaceOrComment()
-	if token.typ == itemString || token.typ == itemMultiString {
-		node = t.newString(token.value)
-		return
+	switch token.typ {
+	case itemString, itemMultiString, itemIdentifier:
+		return t.newString(token.value)
+	case itemListStart:
+		return t.parseList()
+	case itemBlockStart:
+		return t.parseMap()
 	}
 
-	if token.typ == itemListStart {
-		node = t.parseList()
-		return
-	}
-
-	t.errorf("unexpected token %v, expected list or string", token)
+	t.errorf("unexpected token %v, expected list, map or string", token)
 	return
 }
 
@@ -209,16 +232,34 @@ func (t *Tree) parseList() *ListNode {
 	// first item in the buffer is list start token
 	l := t.newList()
 	for {
-		token := t.next()
-		if token.typ == itemString {
-			l.append(t.newString(token.value))
-		} else if token.typ == itemComma || token.typ == itemSpace {
+		token := t.nextNonSpaceOrComment()
+		if token.typ == itemComma {
 			// ignore
 		} else if token.typ == itemListEnd {
 			break
 		} else {
-			t.errorf("unexpected token %v, expected string or comma", token)
+			t.backup()
+			l.append(t.parseValue())
 		}
 	}
 	return l
 }
+
+func (t *Tree) parseMap() *MapNode {
+	// first item in the buffer is map start token
+	m := t.newMap()
+	for {
+		token := t.nextNonSpaceOrComment()
+		switch token.typ {
+		case itemBlockEnd:
+			return m
+		case itemComma:
+			// ignore
+		case itemIdentifier, itemString:
+			// quoted keys may contain any character, e.g. "Content-Type"
+			m.Entries = append(m.Entries, t.parseExpression(token))
+		default:
+			t.errorf("unexpected token %v, expected map key", token)
+		}
+	}
+}
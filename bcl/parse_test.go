@@ -11,6 +11,11 @@ func TestParseFailures(t *testing.T) {
 		`assertion "string"`,
 		`assertion "string" "string"`,
 		`assertion "string" "string" { abc = "123"`,
+		`locals { abc = "123"`,
+		`locals abc { }`,
+		`step "a" "b" { form = { a = } }`,
+		`step "a" "b" { form = { a = "1" }`,
+		`step "a" "b" { multipart { file "a" { } }`,
 	}
 
 	for _, test := range tests {
@@ -42,3 +47,133 @@ func TestParse(t *testing.T) {
 		t.Errorf("expected 2 nodes at the root, got %v", len(tr.Root.Nodes))
 	}
 }
+
+func TestBlockPath(t *testing.T) {
+	tree := New()
+	tree.FileName = "/tests/api/main.bcl"
+	if _, err := tree.Parse(`step "http_request" "step1" { body_file = "body.json" }`); err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	block := tree.Root.Nodes[0].(*BlockNode)
+	tests := map[string]string{
+		"body.json":         "/tests/api/body.json",
+		"../data/body.json": "/tests/data/body.json",
+		"/tmp/body.json":    "/tmp/body.json",
+	}
+	for name, expected := range tests {
+		if path := block.Path(name); path != expected {
+			t.Errorf("expected %q, got %q", expected, path)
+		}
+	}
+
+	parsed, _ := Parse(`step "http_request" "step1" { }`)
+	if path := parsed.Root.Nodes[0].(*BlockNode).Path("body.json"); path != "body.json" {
+		t.Errorf("expected path relative to working directory, got %q", path)
+	}
+}
+
+func TestParseBlockWithoutName(t *testing.T) {
+	tr, err := Parse(`
+	locals {
+		base_url = "http://example.com"
+	}
+	`)
+
+	if err != nil {
+		t.Errorf("parse failed: %v", err)
+	}
+
+	block := tr.Root.Nodes[0].(*BlockNode)
+	if string(block.Id.Text) != "locals" || len(block.Driver.Text) != 0 || len(block.Name.Text) != 0 {
+		t.Errorf("unexpected block %v", block)
+	}
+
+	if len(block.Expressions) != 1 {
+		t.Errorf("expected 1 expression, got %v", len(block.Expressions))
+	}
+}
+
+func TestParseValues(t *testing.T) {
+	tr, err := Parse(`
+	step "http_step" "step1" {
+		attempts = 3, backoff = "exponential"
+		ratio = 1.5
+		on_status = [502, 503]
+		query = [["a", "1"], ["a", "2"]]
+		form = {
+			name = "value"
+			"Content-Type" = "text/plain", tags = ["a", "b"]
+		}
+	}
+	`)
+
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	block := tr.Root.Nodes[0].(*BlockNode)
+	if len(block.Expressions) != 6 {
+		t.Fatalf("expected 6 expressions, got %v", len(block.Expressions))
+	}
+
+	if value, _ := block.Expressions[0].ValueAsString(); value != "3" {
+		t.Errorf("expected bare value 3, got %q", value)
+	}
+
+	if value, _ := block.Expressions[2].ValueAsString(); value != "1.5" {
+		t.Errorf("expected bare value 1.5, got %q", value)
+	}
+
+	if list, _ := block.Expressions[3].ValueAsList(); list.String() != `["502", "503"]` {
+		t.Errorf("unexpected list %v", list)
+	}
+
+	if list, _ := block.Expressions[4].ValueAsList(); list.String() != `[["a", "1"], ["a", "2"]]` {
+		t.Errorf("unexpected list %v", list)
+	}
+
+	m, err := block.Expressions[5].ValueAsMap()
+	if err != nil {
+		t.Fatalf("expected map: %v", err)
+	}
+
+	if m.String() != `{name = "value", Content-Type = "text/plain", tags = ["a", "b"]}` {
+		t.Errorf("unexpected map %v", m)
+	}
+}
+
+func TestParseNestedBlocks(t *testing.T) {
+	tr, err := Parse(`
+	step "http_step" "step1" {
+		method = "POST"
+
+		multipart {
+			title = "avatar"
+
+			file "avatar" {
+				path = "fixtures/a.png"
+			}
+		}
+	}
+	`)
+
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	block := tr.Root.Nodes[0].(*BlockNode)
+	if len(block.Expressions) != 1 || len(block.Blocks) != 1 {
+		t.Fatalf("unexpected block %v", block)
+	}
+
+	multipart := block.Blocks[0]
+	if string(multipart.Id.Text) != "multipart" || len(multipart.Expressions) != 1 || len(multipart.Blocks) != 1 {
+		t.Fatalf("unexpected block %v", multipart)
+	}
+
+	file := multipart.Blocks[0]
+	if string(file.Id.Text) != "file" || string(file.Name.Text) != "avatar" || len(file.Expressions) != 1 {
+		t.Errorf("unexpected block %v", file)
+	}
+}
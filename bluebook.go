@@ -9,6 +9,7 @@ import (
 	"io/ioutil"
 	"os"
 	"strings"
+	"time"
 )
 
 func init() {
@@ -66,6 +67,7 @@ func parseFile(tree *bcl.Tree, fileName string) error {
 		return err
 	}
 
+	tree.FileName = fileName
 	_, err = tree.Parse(string(data))
 	return err
 }
@@ -109,16 +111,34 @@ func main() {
 			Name:    "run",
 			Aliases: []string{"r"},
 			Usage:   "run tests",
+			Flags: []cli.Flag{
+				cli.Int64Flag{
+					Name:  "seed",
+					Usage: "seed for random and fake data, reuse it to reproduce a run",
+				},
+				cli.BoolFlag{
+					Name:  "update-snapshots",
+					Usage: "write missing and changed response snapshots instead of failing",
+				},
+			},
 			Action: func(c *cli.Context) error {
 				testCaseName := c.Args().Get(0)
 
+				options := evaluator.Options{
+					Seed:            time.Now().UnixNano(),
+					UpdateSnapshots: c.Bool("update-snapshots"),
+				}
+				if c.IsSet("seed") {
+					options.Seed = c.Int64("seed")
+				}
+
 				tree, err := parseFiles()
 
 				if err != nil {
 					return cli.NewExitError(fmt.Sprintf("%s", err), -1)
 				}
 
-				err = evaluator.Exec(tree, testCaseName)
+				err = evaluator.Exec(tree, testCaseName, options)
 				if err != nil {
 					return cli.NewExitError(fmt.Sprintf("%s", err), -1)
 				}
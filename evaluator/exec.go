@@ -6,16 +6,25 @@ import (
 	"github.com/bluebookrun/bluebook/bcl"
 	"github.com/bluebookrun/bluebook/resource"
 	"github.com/bluebookrun/bluebook/resource/http_assertion"
+	"github.com/bluebookrun/bluebook/resource/http_client"
 	"github.com/bluebookrun/bluebook/resource/http_step"
 	"github.com/bluebookrun/bluebook/resource/http_test"
 	"github.com/bluebookrun/bluebook/resource/http_variable"
+	"github.com/bluebookrun/bluebook/resource/oauth2_token"
 	"github.com/bluebookrun/bluebook/resource/system_variable"
 	"os"
+	"sort"
 	"strings"
 )
 
 var globalVariables = map[string]string{}
 
+// Options control test execution
+type Options struct {
+	Seed            int64 // seed for random data generators, same seed generates same data
+	UpdateSnapshots bool  // write snapshots of matches_snapshot assertions instead of comparing
+}
+
 type evaluatorState struct {
 	refToResourceMap map[string]resource.Resource
 	idToResourceMap  map[string]resource.Resource
@@ -24,6 +33,10 @@ type evaluatorState struct {
 func loadVariable(variableBlock *bcl.BlockNode) error {
 	variableName := string(variableBlock.Name.Text)
 
+	if len(variableBlock.Blocks) != 0 {
+		return fmt.Errorf("unknown block %q", variableBlock.Blocks[0].Id.Text)
+	}
+
 	if value, ok := os.LookupEnv("BVAR_" + variableName); ok {
 		globalVariables[variableName] = value
 		return nil
@@ -40,7 +53,48 @@ func loadVariable(variableBlock *bcl.BlockNode) error {
 	return fmt.Errorf("variable %s is missing default value", variableName)
 }
 
+// settings block configures defaults for all tests
+func loadSettings(settingsBlock *bcl.BlockNode, executionContext *resource.ExecutionContext) error {
+	options := &http_client.Options{}
+
+	if len(settingsBlock.Blocks) != 0 {
+		return fmt.Errorf("unknown block %q", settingsBlock.Blocks[0].Id.Text)
+	}
+
+	for _, expression := range settingsBlock.Expressions {
+		if string(expression.Field.Text) == "cookies" {
+			value, err := expression.ValueAsString()
+			if err != nil {
+				return err
+			}
+			if err := http_client.ValidateCookies(value); err != nil {
+				return err
+			}
+			executionContext.Cookies = value
+			continue
+		}
+
+		ok, err := options.Parse(expression)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			return fmt.Errorf("unknown setting %q", expression.Field.Text)
+		}
+	}
+
+	if err := options.Validate(); err != nil {
+		return err
+	}
+
+	executionContext.ClientOptions = executionContext.ClientOptions.Merge(options)
+	return nil
+}
+
 func initializeDrivers(tree *bcl.Tree, executionContext *resource.ExecutionContext) error {
+	locals := make(map[string]*localDefinition)
+
 	for _, node := range tree.Root.Nodes {
 		// all nodes at the root must be block nodes
 		if node.Type() != bcl.NodeBlock {
@@ -67,6 +121,8 @@ func initializeDrivers(tree *bcl.Tree, executionContext *resource.ExecutionConte
 				res, err = http_variable.New(nodeBlock)
 			case "system_variable":
 				res, err = system_variable.New(nodeBlock)
+			case "oauth2_token":
+				res, err = oauth2_token.New(nodeBlock)
 			default:
 				return fmt.Errorf("Unsupported resource: %s", nodeBlock.Ref())
 			}
@@ -83,18 +139,33 @@ func initializeDrivers(tree *bcl.Tree, executionContext *resource.ExecutionConte
 			if err := loadVariable(nodeBlock); err != nil {
 				return fmt.Errorf("Failed to load variable: %s", err.Error())
 			}
+		} else if blockId == "settings" {
+			if err := loadSettings(nodeBlock, executionContext); err != nil {
+				return fmt.Errorf("Failed to load settings: %s", err.Error())
+			}
+		} else if blockId == "locals" {
+			if err := loadLocals(nodeBlock, locals); err != nil {
+				return fmt.Errorf("Failed to load locals: %s", err.Error())
+			}
 		} else {
 			return fmt.Errorf("Unknown configuration block type: %s", nodeBlock.Id.Text)
 		}
 	}
 
+	// locals can use variables defined anywhere in the configuration
+	if err := evaluateLocals(locals, executionContext); err != nil {
+		return fmt.Errorf("Failed to evaluate locals: %s", err.Error())
+	}
+
 	return nil
 }
 
 // executes parse tree
-func Exec(tree *bcl.Tree, testCaseName string) error {
+func Exec(tree *bcl.Tree, testCaseName string, options Options) error {
 	numFailedTests := 0
 	executionContext := resource.NewExecutionContext()
+	executionContext.Seed = options.Seed
+	executionContext.UpdateSnapshots = options.UpdateSnapshots
 
 	if err := initializeDrivers(tree, executionContext); err != nil {
 		return err
@@ -107,7 +178,15 @@ func Exec(tree *bcl.Tree, testCaseName string) error {
 		}
 	}
 
-	for ref, r := range executionContext.ReferenceToResourceMap {
+	// tests run in a stable order so that seeded data is reproducible
+	refs := make([]string, 0, len(executionContext.ReferenceToResourceMap))
+	for ref := range executionContext.ReferenceToResourceMap {
+		refs = append(refs, ref)
+	}
+	sort.Strings(refs)
+
+	for _, ref := range refs {
+		r := executionContext.ReferenceToResourceMap[ref]
 		if testCaseName == "" {
 			if strings.HasPrefix(ref, "http_test.") {
 				fmt.Printf("%s\n", ref)
@@ -141,6 +220,7 @@ func Exec(tree *bcl.Tree, testCaseName string) error {
 	}
 
 	if numFailedTests > 0 {
+		fmt.Printf("Random seed: %d\n", options.Seed)
 		return fmt.Errorf("%d tests failed", numFailedTests)
 	} else {
 		fmt.Printf("All tests passed\n")
@@ -0,0 +1,28 @@
+package evaluator
+
+import (
+	"github.com/bluebookrun/bluebook/bcl"
+	"github.com/bluebookrun/bluebook/resource"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestUnknownBlock(t *testing.T) {
+	defer func() { globalVariables = map[string]string{} }()
+
+	tests := map[string]string{
+		`variable "host" { default = "localhost", auth "basic" { username = "a" } }`: `Failed to load variable: unknown block "auth"`,
+		`settings { timeout = "5s", auth "basic" { username = "a" } }`:               `Failed to load settings: unknown block "auth"`,
+		`locals { a = "1", auth "basic" { username = "a" } }`:                        `Failed to load locals: unknown block "auth"`,
+	}
+
+	for text, expected := range tests {
+		tree, err := bcl.Parse(text)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		err = initializeDrivers(tree, resource.NewExecutionContext())
+		assert.EqualError(t, err, expected, text)
+	}
+}
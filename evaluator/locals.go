@@ -0,0 +1,140 @@
+package evaluator
+
+import (
+	"fmt"
+	"github.com/bluebookrun/bluebook/bcl"
+	"github.com/bluebookrun/bluebook/interpolator"
+	"github.com/bluebookrun/bluebook/resource"
+	"sort"
+	"strings"
+)
+
+// local value definitions collected from all locals blocks
+type localDefinition struct {
+	name         string
+	expression   string
+	dependencies []string // names of other local values used in the expression
+}
+
+func loadLocals(localsBlock *bcl.BlockNode, locals map[string]*localDefinition) error {
+	if len(localsBlock.Blocks) != 0 {
+		return fmt.Errorf("unknown block %q", localsBlock.Blocks[0].Id.Text)
+	}
+
+	for _, expression := range localsBlock.Expressions {
+		name := string(expression.Field.Text)
+		if _, ok := locals[name]; ok {
+			return fmt.Errorf("local value %q is defined more than once", name)
+		}
+
+		value, err := expression.ValueAsString()
+		if err != nil {
+			return err
+		}
+
+		references, err := interpolator.References(value)
+		if err != nil {
+			return fmt.Errorf("local value %q: %s", name, err.Error())
+		}
+
+		local := &localDefinition{
+			name:         name,
+			expression:   value,
+			dependencies: make([]string, 0),
+		}
+
+		for _, reference := range references {
+			if strings.HasPrefix(reference, "local.") {
+				dependency, _ := interpolator.SplitPath(strings.TrimPrefix(reference, "local."))
+				local.dependencies = append(local.dependencies, dependency)
+			}
+		}
+
+		locals[name] = local
+	}
+
+	return nil
+}
+
+// sortLocals orders local values so that every value comes after
+// the values it depends on.
+func sortLocals(locals map[string]*localDefinition) ([]*localDefinition, error) {
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+
+	state := make(map[string]int)
+	sorted := make([]*localDefinition, 0, len(locals))
+	path := make([]string, 0)
+
+	var visit func(name string) error
+	visit = func(name string) error {
+		local, ok := locals[name]
+		if !ok {
+			return fmt.Errorf("local value %q is not defined", name)
+		}
+
+		switch state[name] {
+		case visited:
+			return nil
+		case visiting:
+			return fmt.Errorf("local values have a dependency cycle: %s -> %s",
+				strings.Join(path, " -> "), name)
+		}
+
+		state[name] = visiting
+		path = append(path, name)
+
+		for _, dependency := range local.dependencies {
+			if err := visit(dependency); err != nil {
+				return err
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[name] = visited
+		sorted = append(sorted, local)
+		return nil
+	}
+
+	// visit in a stable order so that errors are reproducible
+	names := make([]string, 0, len(locals))
+	for name := range locals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := visit(name); err != nil {
+			return nil, err
+		}
+	}
+
+	return sorted, nil
+}
+
+// evaluateLocals evaluates local values in dependency order and stores them
+// in the execution context. Variables must be loaded beforehand.
+func evaluateLocals(locals map[string]*localDefinition, executionContext *resource.ExecutionContext) error {
+	sorted, err := sortLocals(locals)
+	if err != nil {
+		return err
+	}
+
+	ctx := executionContext.Copy()
+	for variable, value := range globalVariables {
+		ctx.SetVariable(variable, value)
+	}
+
+	for _, local := range sorted {
+		value, err := interpolator.EvalValue(local.expression, ctx)
+		if err != nil {
+			return fmt.Errorf("local value %q: %s", local.name, err.Error())
+		}
+		executionContext.SetLocal(local.name, value)
+	}
+
+	return nil
+}
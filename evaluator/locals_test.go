@@ -0,0 +1,77 @@
+package evaluator
+
+import (
+	"github.com/bluebookrun/bluebook/bcl"
+	"github.com/bluebookrun/bluebook/resource"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func parseLocals(t *testing.T, text string) (map[string]*localDefinition, error) {
+	tree, err := bcl.Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	locals := make(map[string]*localDefinition)
+	for _, node := range tree.Root.Nodes {
+		if err := loadLocals(node.(*bcl.BlockNode), locals); err != nil {
+			return nil, err
+		}
+	}
+	return locals, nil
+}
+
+func TestEvaluateLocals(t *testing.T) {
+	globalVariables = map[string]string{
+		"scheme": "http",
+		"host":   "localhost",
+	}
+	defer func() { globalVariables = map[string]string{} }()
+
+	locals, err := parseLocals(t, `
+	locals {
+		users_url = "${local.base_url}/users"
+		base_url = "${var.scheme}://${var.host}:${local.port}"
+	}
+
+	locals {
+		port = "8080"
+		ports = "${jsonpath("[1, 2]", "$[*]")}"
+	}
+	`)
+	assert.Nil(t, err)
+
+	ctx := resource.NewExecutionContext()
+	assert.Nil(t, evaluateLocals(locals, ctx))
+	assert.Equal(t, map[string]interface{}{
+		"port":      "8080",
+		"base_url":  "http://localhost:8080",
+		"users_url": "http://localhost:8080/users",
+		"ports":     []interface{}{int64(1), int64(2)},
+	}, ctx.Locals)
+}
+
+func TestLocalsFailures(t *testing.T) {
+	_, err := parseLocals(t, `
+	locals { a = "1" }
+	locals { a = "2" }
+	`)
+	assert.NotNil(t, err)
+
+	tests := []string{
+		`locals {
+			a = "${local.b}"
+			b = "${local.c}"
+			c = "${local.a}"
+		}`,
+		`locals { a = "${local.a}" }`,
+		`locals { a = "${local.undefined}" }`,
+	}
+
+	for _, test := range tests {
+		locals, err := parseLocals(t, test)
+		assert.Nil(t, err)
+		assert.NotNil(t, evaluateLocals(locals, resource.NewExecutionContext()), test)
+	}
+}
@@ -0,0 +1,34 @@
+package interpolator
+
+import (
+	"fmt"
+	"github.com/bluebookrun/bluebook/jsonpath"
+	"github.com/bluebookrun/bluebook/resource"
+)
+
+type function func(args []interface{}) (interface{}, error)
+
+// built-in functions available inside template blocks
+var functions = map[string]function{
+	"jsonpath": jsonpathFunction,
+}
+
+// jsonpath(document, path) selects values from a JSON document, e.g.
+// ${jsonpath(var.payload, "$.items[*].id")}
+func jsonpathFunction(args []interface{}) (interface{}, error) {
+	if len(args) != 2 {
+		return nil, fmt.Errorf("expected 2 arguments, got %d", len(args))
+	}
+
+	path, ok := args[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("path must be a string")
+	}
+
+	document, err := resource.ToDocument(args[0])
+	if err != nil {
+		return nil, err
+	}
+
+	return jsonpath.Get(document, path)
+}
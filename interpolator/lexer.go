@@ -20,6 +20,10 @@ const (
 	itemError         itemType = iota // error, value is error text
 	itemText                          // normal text, not part of the template string
 	itemIdentifier                    // variable identifier, e.g. step.http.step1.id
+	itemString                        // quoted string inside template block, e.g. "$.items"
+	itemLeftParen                     // (
+	itemRightParen                    // )
+	itemComma                         // ,
 	itemTemplateStart                 // ${
 	itemTemplateEnd                   // }
 	itemEOF
@@ -61,6 +65,10 @@ func (l *lexer) emit(t itemType) {
 	l.start = l.pos
 }
 
+func (l *lexer) ignore() {
+	l.start = l.pos
+}
+
 func (l *lexer) next() rune {
 	if int(l.pos) >= len(l.input) {
 		l.width = 0
@@ -146,11 +154,60 @@ func lexTemplateEnd(l *lexer) stateFn {
 func lexTemplate(l *lexer) stateFn {
 	for {
 		c := l.next()
-		if c == '}' {
+		switch {
+		case c == eof:
+			return l.errorf("unterminated template string")
+		case isSpace(c):
+			l.ignore()
+		case c == '}':
 			l.backup()
-			l.emit(itemIdentifier)
 			return lexTemplateEnd
+		case c == '(':
+			l.emit(itemLeftParen)
+		case c == ')':
+			l.emit(itemRightParen)
+		case c == ',':
+			l.emit(itemComma)
+		case c == '"':
+			return lexQuote
+		default:
+			return lexIdentifier
 		}
 	}
-	return l.errorf("unterminated template string")
+}
+
+// identifiers include everything up to the next delimiter, so references
+// like var.items[0].name are lexed as a single item
+func lexIdentifier(l *lexer) stateFn {
+	for {
+		c := l.next()
+		if c == eof || isSpace(c) || isDelimiter(c) {
+			l.backup()
+			l.emit(itemIdentifier)
+			return lexTemplate
+		}
+	}
+}
+
+// opening double quote is already consumed
+func lexQuote(l *lexer) stateFn {
+	for {
+		switch l.next() {
+		case eof:
+			return l.errorf("unterminated quoted string")
+		case '\\':
+			l.next()
+		case '"':
+			l.emit(itemString)
+			return lexTemplate
+		}
+	}
+}
+
+func isSpace(r rune) bool {
+	return r == ' ' || r == '\t' || r == '\r' || r == '\n'
+}
+
+func isDelimiter(r rune) bool {
+	return r == '}' || r == '(' || r == ')' || r == ',' || r == '"'
 }
@@ -2,12 +2,16 @@ package interpolator
 
 import (
 	"fmt"
+	"github.com/bluebookrun/bluebook/jsonpath"
 	"github.com/bluebookrun/bluebook/resource"
 	"strings"
 )
 
 type Node interface {
 	Eval(ctx *resource.ExecutionContext) (string, error)
+	// EvalValue returns evaluated node without converting it to a string,
+	// e.g. lists and objects captured from JSON responses
+	EvalValue(ctx *resource.ExecutionContext) (interface{}, error)
 }
 
 type NodeText struct {
@@ -26,6 +30,30 @@ func (nt *NodeText) Eval(ctx *resource.ExecutionContext) (string, error) {
 	return nt.Value, nil
 }
 
+func (nt *NodeText) EvalValue(ctx *resource.ExecutionContext) (interface{}, error) {
+	return nt.Value, nil
+}
+
+type NodeString struct {
+	Tree  *Tree
+	Value string
+}
+
+func (t *Tree) newString(value string) Node {
+	return &NodeString{
+		Tree:  t,
+		Value: value,
+	}
+}
+
+func (ns *NodeString) Eval(ctx *resource.ExecutionContext) (string, error) {
+	return ns.Value, nil
+}
+
+func (ns *NodeString) EvalValue(ctx *resource.ExecutionContext) (interface{}, error) {
+	return ns.Value, nil
+}
+
 type NodeReference struct {
 	Tree  *Tree
 	Value string
@@ -39,30 +67,118 @@ func (t *Tree) newReference(value string) Node {
 }
 
 func (nr *NodeReference) Eval(ctx *resource.ExecutionContext) (string, error) {
-	tokens := strings.Split(nr.Value, ".")
+	value, err := nr.EvalValue(ctx)
+	if err != nil {
+		return "", err
+	}
+	return resource.ToString(value)
+}
 
+func (nr *NodeReference) EvalValue(ctx *resource.ExecutionContext) (interface{}, error) {
 	if strings.HasPrefix(nr.Value, "var.") {
-		value := ctx.GetVariable(tokens[1])
-		if value != nil {
-			return *value, nil
+		// var.name or var.name.path[0].to.value
+		name, path := SplitPath(strings.TrimPrefix(nr.Value, "var."))
+		value, ok := ctx.GetVariable(name)
+		if !ok && path != "" {
+			return nil, fmt.Errorf("variable %q is not defined", name)
 		}
-	} else {
-		if len(tokens) != 3 {
-			return "", fmt.Errorf("invalid reference: %s", nr.Value)
+		if !ok {
+			return "", nil
 		}
+		return evalPath(value, path, "variable", name)
+	}
 
-		resourceReference := fmt.Sprintf("%s.%s", tokens[0], tokens[1])
-		attribute := tokens[2]
-
-		r := ctx.GetResourceByReference(resourceReference)
-		if r == nil {
-			return "", fmt.Errorf("resource not found: %q", resourceReference)
+	if strings.HasPrefix(nr.Value, "local.") {
+		name, path := SplitPath(strings.TrimPrefix(nr.Value, "local."))
+		value, ok := ctx.GetLocal(name)
+		if !ok {
+			return nil, fmt.Errorf("local value %q is not defined", name)
 		}
+		return evalPath(value, path, "local value", name)
+	}
+
+	tokens := strings.Split(nr.Value, ".")
+	if len(tokens) != 3 {
+		return "", fmt.Errorf("invalid reference: %s", nr.Value)
+	}
+
+	resourceReference := fmt.Sprintf("%s.%s", tokens[0], tokens[1])
+	attribute := tokens[2]
 
-		if attribute := r.GetAttribute(attribute); attribute != nil {
-			return *attribute, nil
+	r := ctx.GetResourceByReference(resourceReference)
+	if r == nil {
+		return "", fmt.Errorf("resource not found: %q", resourceReference)
+	}
+
+	if refresher, ok := r.(resource.Refresher); ok && attribute != "id" {
+		if err := refresher.Refresh(ctx); err != nil {
+			return "", fmt.Errorf("%s: %s", resourceReference, err.Error())
 		}
 	}
 
+	if attribute := r.GetAttribute(attribute); attribute != nil {
+		return *attribute, nil
+	}
+
 	return "", nil
 }
+
+type NodeFunction struct {
+	Tree *Tree
+	Name string
+	Args []Node
+}
+
+func (t *Tree) newFunction(name string, args []Node) Node {
+	return &NodeFunction{
+		Tree: t,
+		Name: name,
+		Args: args,
+	}
+}
+
+func (nf *NodeFunction) Eval(ctx *resource.ExecutionContext) (string, error) {
+	value, err := nf.EvalValue(ctx)
+	if err != nil {
+		return "", err
+	}
+	return resource.ToString(value)
+}
+
+func (nf *NodeFunction) EvalValue(ctx *resource.ExecutionContext) (interface{}, error) {
+	args := make([]interface{}, len(nf.Args))
+	for i, arg := range nf.Args {
+		value, err := arg.EvalValue(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = value
+	}
+
+	value, err := functions[nf.Name](args)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %s", nf.Name, err.Error())
+	}
+	return value, nil
+}
+
+// SplitPath splits variable reference into a name and a JSON path,
+// e.g. user.roles[0] becomes user and .roles[0]
+func SplitPath(reference string) (string, string) {
+	if i := strings.IndexAny(reference, ".["); i >= 0 {
+		return reference[:i], reference[i:]
+	}
+	return reference, ""
+}
+
+func evalPath(value interface{}, path string, kind string, name string) (interface{}, error) {
+	if path == "" {
+		return value, nil
+	}
+
+	document, err := resource.ToDocument(value)
+	if err != nil {
+		return nil, fmt.Errorf("%s %q: %s", kind, name, err.Error())
+	}
+	return jsonpath.Get(document, "$"+path)
+}
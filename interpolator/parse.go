@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"fmt"
 	"runtime"
+	"strconv"
 
 	"github.com/bluebookrun/bluebook/resource"
 )
@@ -44,6 +45,58 @@ func Eval(text string, ctx *resource.ExecutionContext) (string, error) {
 	return buffer.String(), nil
 }
 
+// EvalValue evaluates text that consists of a single template block without
+// converting the result to a string, e.g. "${var.items}" evaluates to a list.
+// Any other text is evaluated to a string.
+func EvalValue(text string, ctx *resource.ExecutionContext) (interface{}, error) {
+	tree, err := Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	nodes := make([]Node, 0, len(tree.Root))
+	for _, node := range tree.Root {
+		if textNode, ok := node.(*NodeText); ok && textNode.Value == "" {
+			continue
+		}
+		nodes = append(nodes, node)
+	}
+
+	if len(nodes) == 1 {
+		return nodes[0].EvalValue(ctx)
+	}
+	return Eval(text, ctx)
+}
+
+// References returns all references used in text, e.g. var.name or
+// http_step.name.id
+func References(text string) ([]string, error) {
+	tree, err := Parse(text)
+	if err != nil {
+		return nil, err
+	}
+
+	references := make([]string, 0)
+	for _, node := range tree.Root {
+		references = appendReferences(references, node)
+	}
+	return references, nil
+}
+
+func appendReferences(references []string, node Node) []string {
+	switch node := node.(type) {
+	case *NodeReference:
+		if node.Value != "" {
+			references = append(references, node.Value)
+		}
+	case *NodeFunction:
+		for _, arg := range node.Args {
+			references = appendReferences(references, arg)
+		}
+	}
+	return references
+}
+
 func (t *Tree) startParse(lex *lexer) {
 	t.Root = nil
 	t.lex = lex
@@ -127,14 +180,67 @@ func (t *Tree) parse() {
 
 func (t *Tree) parseTemplate() Node {
 	token := t.next()
-	if token.typ != itemIdentifier {
-		t.errorf("expected identifier token inside template block, got %v", token)
+	if token.typ == itemTemplateEnd {
+		// empty template block
+		return t.newReference("")
 	}
 
+	t.backup()
+	node := t.parseExpression()
+
 	templateEndToken := t.next()
 	if templateEndToken.typ != itemTemplateEnd {
 		t.errorf("expected template end token, got %v", templateEndToken)
 	}
 
-	return t.newReference(token.value)
+	return node
+}
+
+// parses a reference, a quoted string or a function call
+func (t *Tree) parseExpression() Node {
+	token := t.next()
+	switch token.typ {
+	case itemString:
+		value, err := strconv.Unquote(token.value)
+		if err != nil {
+			t.errorf("invalid quoted string %s: %s", token.value, err.Error())
+		}
+		return t.newString(value)
+	case itemIdentifier:
+		if t.peek().typ == itemLeftParen {
+			t.next()
+			return t.parseFunction(token.value)
+		}
+		return t.newReference(token.value)
+	}
+
+	t.errorf("expected identifier or string inside template block, got %v", token)
+	return nil
+}
+
+// parses function arguments, left parenthesis is already consumed
+func (t *Tree) parseFunction(name string) Node {
+	if _, ok := functions[name]; !ok {
+		t.errorf("unknown function %q", name)
+	}
+
+	args := make([]Node, 0)
+	if t.peek().typ == itemRightParen {
+		t.next()
+		return t.newFunction(name, args)
+	}
+
+	for {
+		args = append(args, t.parseExpression())
+
+		token := t.next()
+		if token.typ == itemRightParen {
+			break
+		}
+		if token.typ != itemComma {
+			t.errorf("expected comma or right parenthesis, got %v", token)
+		}
+	}
+
+	return t.newFunction(name, args)
 }
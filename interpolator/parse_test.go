@@ -1,6 +1,7 @@
 package interpolator
 
 import (
+	"github.com/bluebookrun/bluebook/resource"
 	"github.com/stretchr/testify/assert"
 	"testing"
 )
@@ -38,3 +39,82 @@ func TestEvalFailsWhenNoContext(t *testing.T) {
 
 	assert.NotNil(t, err)
 }
+
+func TestParseFunction(t *testing.T) {
+	tree, err := Parse(`${jsonpath(var.payload, "$.items[*]")}`)
+	assert.Nil(t, err)
+	assert.Equal(t, 3, len(tree.Root))
+
+	function, ok := tree.Root[1].(*NodeFunction)
+	assert.True(t, ok)
+	assert.Equal(t, "jsonpath", function.Name)
+	assert.Equal(t, 2, len(function.Args))
+}
+
+func TestParseFailures(t *testing.T) {
+	tests := []string{
+		`${unknown(var.a)}`,
+		`${jsonpath(var.a "b")}`,
+		`${"unterminated}`,
+		`${var.a`,
+	}
+
+	for _, test := range tests {
+		_, err := Parse(test)
+		assert.NotNil(t, err, test)
+	}
+}
+
+func TestEvalJSONPaths(t *testing.T) {
+	ctx := resource.NewExecutionContext()
+	ctx.SetVariable("user", map[string]interface{}{
+		"roles": []interface{}{
+			map[string]interface{}{"name": "admin"},
+		},
+	})
+	ctx.SetVariable("payload", `{"items": [{"id": 1}, {"id": 2}]}`)
+
+	cases := map[string]string{
+		`${var.user.roles[0].name}`:                   "admin",
+		`${var.user['roles'][0]['name']}`:             "admin",
+		`${var.user.roles}`:                           `[{"name":"admin"}]`,
+		`${jsonpath(var.payload, "$.items[*].id")}`:   "[1,2]",
+		`id=${jsonpath(var.user, "$.roles[0].name")}`: "id=admin",
+		`${var.missing}`:                              "",
+	}
+
+	for text, expected := range cases {
+		value, err := Eval(text, ctx)
+		assert.Nil(t, err, text)
+		assert.Equal(t, expected, value, text)
+	}
+
+	_, err := Eval(`${var.user.groups[0]}`, ctx)
+	assert.NotNil(t, err)
+
+	_, err = Eval(`${var.missing.x}`, ctx)
+	assert.EqualError(t, err, `variable "missing" is not defined`)
+}
+
+func TestEvalValue(t *testing.T) {
+	ctx := resource.NewExecutionContext()
+	ctx.SetVariable("items", []interface{}{int64(1)})
+	ctx.SetLocal("base_url", "http://localhost")
+
+	value, err := EvalValue(`${var.items}`, ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{int64(1)}, value)
+
+	value, err = EvalValue(`${local.base_url}/items/${var.items[0]}`, ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "http://localhost/items/1", value)
+
+	_, err = EvalValue(`${local.undefined}`, ctx)
+	assert.NotNil(t, err)
+}
+
+func TestReferences(t *testing.T) {
+	references, err := References(`${local.a}/${jsonpath(var.b, "$.c")}/${http_step.d.id}`)
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"local.a", "var.b", "http_step.d.id"}, references)
+}
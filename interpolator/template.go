@@ -0,0 +1,57 @@
+package interpolator
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/bluebookrun/bluebook/resource"
+	"text/template"
+)
+
+// Render executes Go text/template with bluebook variables and functions,
+// e.g. {{ range .var.items }}{{ .id }}{{ end }}. Other references are
+// evaluated with ref, e.g. {{ ref "http_step.login.id" }}.
+func Render(name string, text string, ctx *resource.ExecutionContext) (string, error) {
+	funcs := templateFunctions()
+	funcs["ref"] = func(reference string) (interface{}, error) {
+		return (&NodeReference{Value: reference}).EvalValue(ctx)
+	}
+
+	tmpl, err := template.New(name).
+		Option("missingkey=error").
+		Funcs(funcs).
+		Parse(text)
+	if err != nil {
+		return "", err
+	}
+
+	data := map[string]interface{}{
+		"var":   ctx.Variables,
+		"local": ctx.Locals,
+	}
+
+	var buffer bytes.Buffer
+	if err := tmpl.Execute(&buffer, data); err != nil {
+		return "", err
+	}
+	return buffer.String(), nil
+}
+
+func templateFunctions() template.FuncMap {
+	funcs := template.FuncMap{
+		// json formats value as a JSON document, strings are quoted
+		"json": func(value interface{}) (string, error) {
+			data, err := json.Marshal(value)
+			return string(data), err
+		},
+		// list converts value to a list so it can be used in range actions
+		"list": resource.ToList,
+	}
+
+	for name, f := range functions {
+		f := f
+		funcs[name] = func(args ...interface{}) (interface{}, error) {
+			return f(args)
+		}
+	}
+	return funcs
+}
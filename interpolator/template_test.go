@@ -0,0 +1,65 @@
+package interpolator
+
+import (
+	"github.com/bluebookrun/bluebook/resource"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	ctx := resource.NewExecutionContext()
+	ctx.SetVariable("customer", "John")
+	ctx.SetVariable("items", []interface{}{
+		map[string]interface{}{"id": int64(1), "name": "book"},
+		map[string]interface{}{"id": int64(2), "name": "pen"},
+	})
+	ctx.SetVariable("ids", "[3, 4]")
+
+	text := `{"customer": {{ json .var.customer }}, "items": [
+{{- range $i, $item := .var.items }}{{ if $i }}, {{ end }}{{ $item.id }}{{ end -}}
+], "names": {{ json (jsonpath .var.items "$[*].name") }}, "ids": [
+{{- range $i, $id := list .var.ids }}{{ if $i }}, {{ end }}{{ $id }}{{ end -}}
+]}`
+
+	value, err := Render("order", text, ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, `{"customer": "John", "items": [1, 2], "names": ["book","pen"], "ids": [3, 4]}`, value)
+}
+
+func TestRenderFailures(t *testing.T) {
+	ctx := resource.NewExecutionContext()
+
+	tests := []string{
+		`{{ .var.missing }}`,
+		`{{ range .var }}`,
+		`{{ unknown .var }}`,
+	}
+
+	for _, test := range tests {
+		_, err := Render("test", test, ctx)
+		assert.NotNil(t, err, test)
+	}
+}
+
+// attributeResource has an attribute used in template references
+type attributeResource struct {
+	id string
+}
+
+func (r *attributeResource) Link(ctx *resource.ExecutionContext) error { return nil }
+func (r *attributeResource) Exec(ctx *resource.ExecutionContext) error { return nil }
+func (r *attributeResource) GetAttribute(name string) *string          { return &r.id }
+
+func TestRenderReferences(t *testing.T) {
+	ctx := resource.NewExecutionContext()
+	ctx.SetVariable("user", map[string]interface{}{"name": "John"})
+	ctx.SetLocal("region", "eu")
+	assert.Nil(t, ctx.AddResource("http_step.login", &attributeResource{id: "step-1"}))
+
+	value, err := Render("refs", `{{ ref "var.user.name" }} {{ ref "http_step.login.id" }} {{ .local.region }}`, ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "John step-1 eu", value)
+
+	_, err = Render("refs", `{{ ref "http_step.missing.id" }}`, ctx)
+	assert.NotNil(t, err)
+}
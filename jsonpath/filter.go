@@ -0,0 +1,383 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// filter is a boolean expression of a filter selector, e.g.
+// @.price < 10 && @.tags
+type filter interface {
+	test(node interface{}, root interface{}) bool
+}
+
+type orFilter struct {
+	left, right filter
+}
+
+func (f *orFilter) test(node interface{}, root interface{}) bool {
+	return f.left.test(node, root) || f.right.test(node, root)
+}
+
+type andFilter struct {
+	left, right filter
+}
+
+func (f *andFilter) test(node interface{}, root interface{}) bool {
+	return f.left.test(node, root) && f.right.test(node, root)
+}
+
+type notFilter struct {
+	inner filter
+}
+
+func (f *notFilter) test(node interface{}, root interface{}) bool {
+	return !f.inner.test(node, root)
+}
+
+// existsFilter passes if path selects at least one value, e.g. @.tags
+type existsFilter struct {
+	path *operand
+}
+
+func (f *existsFilter) test(node interface{}, root interface{}) bool {
+	_, ok := f.path.value(node, root)
+	return ok
+}
+
+type compareFilter struct {
+	left, right *operand
+	op          string
+	pattern     *regexp.Regexp // right side of =~
+}
+
+func (f *compareFilter) test(node interface{}, root interface{}) bool {
+	left, leftOk := f.left.value(node, root)
+
+	if f.op == "=~" {
+		s, ok := left.(string)
+		return leftOk && ok && f.pattern.MatchString(s)
+	}
+
+	right, rightOk := f.right.value(node, root)
+
+	switch f.op {
+	case "==":
+		if !leftOk || !rightOk {
+			return leftOk == rightOk
+		}
+		return equal(left, right)
+	case "!=":
+		if !leftOk || !rightOk {
+			return leftOk != rightOk
+		}
+		return !equal(left, right)
+	}
+
+	if !leftOk || !rightOk {
+		return false
+	}
+
+	var c int
+	if l, ok := toFloat(left); ok {
+		r, ok := toFloat(right)
+		if !ok {
+			return false
+		}
+		c = compareFloats(l, r)
+	} else if l, ok := left.(string); ok {
+		r, ok := right.(string)
+		if !ok {
+			return false
+		}
+		c = strings.Compare(l, r)
+	} else {
+		return false
+	}
+
+	switch f.op {
+	case "<":
+		return c < 0
+	case "<=":
+		return c <= 0
+	case ">":
+		return c > 0
+	case ">=":
+		return c >= 0
+	}
+	return false
+}
+
+// operand is a literal or a path relative to the current node (@) or
+// the root ($)
+type operand struct {
+	literal  interface{}
+	path     *Path
+	relative bool
+}
+
+// value returns literal, or the first value selected by path. ok is
+// false if path doesn't select anything.
+func (o *operand) value(node interface{}, root interface{}) (interface{}, bool) {
+	if o.path == nil {
+		return o.literal, true
+	}
+
+	start := root
+	if o.relative {
+		start = node
+	}
+
+	values := o.path.find(start, root)
+	if len(values) == 0 {
+		return nil, false
+	}
+	return values[0], true
+}
+
+func equal(left, right interface{}) bool {
+	if l, ok := toFloat(left); ok {
+		r, ok := toFloat(right)
+		return ok && l == r
+	}
+	return reflect.DeepEqual(left, right)
+}
+
+func compareFloats(l, r float64) int {
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	}
+	return 0
+}
+
+// toFloat converts JSON numbers decoded as float64, int64 or json.Number
+func toFloat(value interface{}) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case int64:
+		return float64(value), true
+	case int:
+		return float64(value), true
+	case json.Number:
+		f, err := value.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// filter parses filter expression, the ? is already consumed
+func (p *parser) filter() (filter, error) {
+	inFilter := p.inFilter
+	p.inFilter = true
+	defer func() { p.inFilter = inFilter }()
+
+	return p.or()
+}
+
+func (p *parser) or() (filter, error) {
+	left, err := p.and()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.skipSpaces(); strings.HasPrefix(p.input[p.pos:], "||"); p.skipSpaces() {
+		p.pos += 2
+		right, err := p.and()
+		if err != nil {
+			return nil, err
+		}
+		left = &orFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) and() (filter, error) {
+	left, err := p.unary()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.skipSpaces(); strings.HasPrefix(p.input[p.pos:], "&&"); p.skipSpaces() {
+		p.pos += 2
+		right, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		left = &andFilter{left: left, right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) unary() (filter, error) {
+	p.skipSpaces()
+
+	switch p.peek() {
+	case '!':
+		p.pos++
+		inner, err := p.unary()
+		if err != nil {
+			return nil, err
+		}
+		return &notFilter{inner: inner}, nil
+	case '(':
+		p.pos++
+		inner, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		p.skipSpaces()
+		if p.peek() != ')' {
+			return nil, fmt.Errorf("expected ) at position %d", p.pos)
+		}
+		p.pos++
+		return inner, nil
+	}
+
+	return p.comparison()
+}
+
+var comparisonOperators = []string{"==", "!=", "<=", ">=", "=~", "<", ">"}
+
+func (p *parser) comparison() (filter, error) {
+	start := p.pos
+	left, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+	op := ""
+	for _, operator := range comparisonOperators {
+		if strings.HasPrefix(p.input[p.pos:], operator) {
+			op = operator
+			break
+		}
+	}
+
+	if op == "" {
+		if left.path == nil {
+			return nil, fmt.Errorf("expected path at position %d", start)
+		}
+		return &existsFilter{path: left}, nil
+	}
+	p.pos += len(op)
+	p.skipSpaces()
+
+	if op == "=~" {
+		pattern, err := p.regexp()
+		if err != nil {
+			return nil, err
+		}
+		return &compareFilter{left: left, op: op, pattern: pattern}, nil
+	}
+
+	right, err := p.operand()
+	if err != nil {
+		return nil, err
+	}
+	return &compareFilter{left: left, right: right, op: op}, nil
+}
+
+var numberPattern = regexp.MustCompile(`^-?[0-9]+(\.[0-9]+)?([eE][-+]?[0-9]+)?`)
+
+func (p *parser) operand() (*operand, error) {
+	p.skipSpaces()
+	rest := p.input[p.pos:]
+
+	switch c := p.peek(); {
+	case c == '@' || c == '$':
+		p.pos++
+		segments, err := p.segments(make([]segment, 0))
+		if err != nil {
+			return nil, err
+		}
+		return &operand{
+			path:     &Path{Text: rest[:len(rest)-len(p.input[p.pos:])], segments: segments},
+			relative: c == '@',
+		}, nil
+	case c == '\'' || c == '"':
+		s, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		return &operand{literal: s}, nil
+	case strings.HasPrefix(rest, "true"):
+		p.pos += 4
+		return &operand{literal: true}, nil
+	case strings.HasPrefix(rest, "false"):
+		p.pos += 5
+		return &operand{literal: false}, nil
+	case strings.HasPrefix(rest, "null"):
+		p.pos += 4
+		return &operand{literal: nil}, nil
+	}
+
+	if number := numberPattern.FindString(rest); number != "" {
+		f, err := strconv.ParseFloat(number, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q at position %d", number, p.pos)
+		}
+		p.pos += len(number)
+		return &operand{literal: f}, nil
+	}
+
+	return nil, fmt.Errorf("expected path or value at position %d", p.pos)
+}
+
+// regexp parses /pattern/ with optional i flag, or quoted pattern
+func (p *parser) regexp() (*regexp.Regexp, error) {
+	start := p.pos
+	var pattern string
+
+	switch p.peek() {
+	case '\'', '"':
+		s, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		pattern = s
+	case '/':
+		p.pos++
+		var b strings.Builder
+		for {
+			if p.pos >= len(p.input) {
+				return nil, fmt.Errorf("unterminated regular expression at position %d", start)
+			}
+			c := p.input[p.pos]
+			p.pos++
+			if c == '/' {
+				break
+			}
+			if c == '\\' && p.peek() == '/' {
+				c = '/'
+				p.pos++
+			} else if c == '\\' && p.pos < len(p.input) {
+				b.WriteByte(c)
+				c = p.input[p.pos]
+				p.pos++
+			}
+			b.WriteByte(c)
+		}
+		pattern = b.String()
+		if p.peek() == 'i' {
+			p.pos++
+			pattern = "(?i)" + pattern
+		}
+	default:
+		return nil, fmt.Errorf("expected regular expression at position %d", p.pos)
+	}
+
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression at position %d: %s", start, err.Error())
+	}
+	return re, nil
+}
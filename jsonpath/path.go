@@ -0,0 +1,642 @@
+package jsonpath
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Path is a compiled JSONPath expression, e.g. $.items[?(@.price < 10)].id
+//
+// Paths without the leading $ are evaluated relative to the root, so dotted
+// property names like data.list[0] keep working.
+//
+// Supported selectors are child names (.name, ['name']), indexes ([0],
+// [-1]), wildcards (.*, [*]), slices ([1:3], [::-1]), unions ([0,2],
+// ['a','b']), recursive descent (..name, ..*) and filters ([?(@.a > 1)]).
+type Path struct {
+	Text     string
+	segments []segment
+}
+
+type segment interface {
+	// appends locations of all values selected from node to out, root
+	// is the whole document for filters
+	locate(node location, root interface{}, out []location) []location
+	// reports whether segment selects at most one value
+	definite() bool
+	String() string
+}
+
+// location is a selected value and where it is stored
+type location struct {
+	value  interface{}
+	parent interface{} // object or array containing the value, nil for the root
+	key    interface{} // string key of object, or int index of array
+}
+
+func (l location) set(value interface{}) {
+	switch parent := l.parent.(type) {
+	case map[string]interface{}:
+		parent[l.key.(string)] = value
+	case []interface{}:
+		parent[l.key.(int)] = value
+	}
+}
+
+type childSegment struct {
+	name string
+}
+
+func (s *childSegment) locate(node location, root interface{}, out []location) []location {
+	if m, ok := node.value.(map[string]interface{}); ok {
+		if value, ok := m[s.name]; ok {
+			out = append(out, location{value, m, s.name})
+		}
+	}
+	return out
+}
+
+func (s *childSegment) definite() bool {
+	return true
+}
+
+func (s *childSegment) String() string {
+	return fmt.Sprintf("[%q]", s.name)
+}
+
+type indexSegment struct {
+	index int
+}
+
+func (s *indexSegment) locate(node location, root interface{}, out []location) []location {
+	if l, ok := node.value.([]interface{}); ok {
+		i := s.index
+		if i < 0 {
+			i += len(l)
+		}
+		if i >= 0 && i < len(l) {
+			out = append(out, location{l[i], l, i})
+		}
+	}
+	return out
+}
+
+func (s *indexSegment) definite() bool {
+	return true
+}
+
+func (s *indexSegment) String() string {
+	return fmt.Sprintf("[%d]", s.index)
+}
+
+type wildcardSegment struct{}
+
+func (s *wildcardSegment) locate(node location, root interface{}, out []location) []location {
+	return appendChildren(node.value, out)
+}
+
+func (s *wildcardSegment) definite() bool {
+	return false
+}
+
+func (s *wildcardSegment) String() string {
+	return "[*]"
+}
+
+// sliceSegment selects array items from start up to, but not including,
+// end. Negative positions count from the end of the array.
+type sliceSegment struct {
+	start *int
+	end   *int
+	step  int
+}
+
+func (s *sliceSegment) locate(node location, root interface{}, out []location) []location {
+	l, ok := node.value.([]interface{})
+	if !ok || s.step == 0 {
+		return out
+	}
+
+	n := len(l)
+	normalize := func(i int) int {
+		if i < 0 {
+			return n + i
+		}
+		return i
+	}
+
+	if s.step > 0 {
+		start, end := 0, n
+		if s.start != nil {
+			start = normalize(*s.start)
+		}
+		if s.end != nil {
+			end = normalize(*s.end)
+		}
+
+		for i := clamp(start, 0, n); i < clamp(end, 0, n); i += s.step {
+			out = append(out, location{l[i], l, i})
+		}
+		return out
+	}
+
+	start, end := n-1, -1
+	if s.start != nil {
+		start = normalize(*s.start)
+	}
+	if s.end != nil {
+		end = normalize(*s.end)
+	}
+
+	for i := clamp(start, -1, n-1); i > clamp(end, -1, n-1); i += s.step {
+		out = append(out, location{l[i], l, i})
+	}
+	return out
+}
+
+func (s *sliceSegment) definite() bool {
+	return false
+}
+
+func (s *sliceSegment) String() string {
+	position := func(i *int) string {
+		if i == nil {
+			return ""
+		}
+		return strconv.Itoa(*i)
+	}
+	return fmt.Sprintf("[%s:%s:%d]", position(s.start), position(s.end), s.step)
+}
+
+// unionSegment selects values of all selectors in order, e.g. [0,2]
+type unionSegment struct {
+	selectors []segment
+}
+
+func (s *unionSegment) locate(node location, root interface{}, out []location) []location {
+	for _, selector := range s.selectors {
+		out = selector.locate(node, root, out)
+	}
+	return out
+}
+
+func (s *unionSegment) definite() bool {
+	return false
+}
+
+func (s *unionSegment) String() string {
+	selectors := make([]string, 0, len(s.selectors))
+	for _, selector := range s.selectors {
+		selectors = append(selectors, strings.Trim(selector.String(), "[]"))
+	}
+	return "[" + strings.Join(selectors, ",") + "]"
+}
+
+// descendantSegment applies selector to the node and all its
+// descendants, e.g. ..name
+type descendantSegment struct {
+	selector segment
+}
+
+func (s *descendantSegment) locate(node location, root interface{}, out []location) []location {
+	out = s.selector.locate(node, root, out)
+	for _, child := range appendChildren(node.value, nil) {
+		out = s.locate(child, root, out)
+	}
+	return out
+}
+
+func (s *descendantSegment) definite() bool {
+	return false
+}
+
+func (s *descendantSegment) String() string {
+	return ".." + s.selector.String()
+}
+
+// filterSegment selects array items or object values that pass the
+// filter expression
+type filterSegment struct {
+	filter filter
+	text   string
+}
+
+func (s *filterSegment) locate(node location, root interface{}, out []location) []location {
+	for _, child := range appendChildren(node.value, nil) {
+		if s.filter.test(child.value, root) {
+			out = append(out, child)
+		}
+	}
+	return out
+}
+
+func (s *filterSegment) definite() bool {
+	return false
+}
+
+func (s *filterSegment) String() string {
+	return "[?(" + s.text + ")]"
+}
+
+// Compile parses JSONPath expression.
+func Compile(text string) (*Path, error) {
+	p := &parser{input: text}
+	segments, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("invalid JSON path %q: %s", text, err.Error())
+	}
+
+	return &Path{
+		Text:     text,
+		segments: segments,
+	}, nil
+}
+
+// Definite reports whether path always selects at most one value.
+func (p *Path) Definite() bool {
+	for _, s := range p.segments {
+		if !s.definite() {
+			return false
+		}
+	}
+	return true
+}
+
+// Find returns all values selected by the path.
+func (p *Path) Find(data interface{}) []interface{} {
+	return p.find(data, data)
+}
+
+// find applies the path to node, filters use root for absolute paths
+func (p *Path) find(node interface{}, root interface{}) []interface{} {
+	locations := p.locate(node, root)
+	values := make([]interface{}, 0, len(locations))
+	for _, l := range locations {
+		values = append(values, l.value)
+	}
+	return values
+}
+
+func (p *Path) locate(node interface{}, root interface{}) []location {
+	locations := []location{{value: node}}
+	for _, s := range p.segments {
+		selected := make([]location, 0, len(locations))
+		for _, l := range locations {
+			selected = s.locate(l, root, selected)
+		}
+		locations = selected
+	}
+	return locations
+}
+
+// Get returns the value selected by a definite path, or a list of all
+// selected values for paths with wildcards. Definite paths that do not
+// resolve to a value are reported as an error.
+func (p *Path) Get(data interface{}) (interface{}, error) {
+	if !p.Definite() {
+		return p.Find(data), nil
+	}
+
+	node := location{value: data}
+	for i, s := range p.segments {
+		selected := s.locate(node, data, nil)
+		if len(selected) == 0 {
+			return nil, fmt.Errorf("JSON path %q not found at %s",
+				p.Text, segmentsString(p.segments[:i+1]))
+		}
+		node = selected[0]
+	}
+	return node.value, nil
+}
+
+// Replace replaces every value selected by the path with the result of
+// replace, objects and arrays of data are modified in place. Returns
+// data, or the replaced root if the path selects it.
+func (p *Path) Replace(data interface{}, replace func(interface{}) interface{}) interface{} {
+	for _, l := range p.locate(data, data) {
+		if l.parent == nil {
+			data = replace(l.value)
+			continue
+		}
+		l.set(replace(l.value))
+	}
+	return data
+}
+
+// Get compiles JSON path and applies it to data.
+func Get(data interface{}, text string) (interface{}, error) {
+	p, err := Compile(text)
+	if err != nil {
+		return nil, err
+	}
+	return p.Get(data)
+}
+
+func segmentsString(segments []segment) string {
+	s := "$"
+	for _, segment := range segments {
+		s += segment.String()
+	}
+	return s
+}
+
+type parser struct {
+	input    string
+	pos      int
+	inFilter bool // names end at operators and spaces in filter paths
+}
+
+func (p *parser) parse() ([]segment, error) {
+	segments := make([]segment, 0)
+
+	if strings.HasPrefix(p.input, "$") {
+		p.pos++
+	} else if p.pos < len(p.input) && p.input[p.pos] != '.' && p.input[p.pos] != '[' {
+		// relative path starts with a property name, e.g. data.key
+		segments = append(segments, &childSegment{name: p.name()})
+	}
+
+	segments, err := p.segments(segments)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.pos < len(p.input) {
+		return nil, fmt.Errorf("unexpected character %q at position %d", p.input[p.pos], p.pos)
+	}
+
+	return segments, nil
+}
+
+// segments parses selectors up to the first character that doesn't
+// start a selector
+func (p *parser) segments(segments []segment) ([]segment, error) {
+	for p.pos < len(p.input) {
+		switch p.input[p.pos] {
+		case '.':
+			p.pos++
+			if p.peek() == '.' {
+				p.pos++
+				s, err := p.descendant()
+				if err != nil {
+					return nil, err
+				}
+				segments = append(segments, &descendantSegment{selector: s})
+				continue
+			}
+
+			if p.peek() == '*' {
+				p.pos++
+				segments = append(segments, &wildcardSegment{})
+				continue
+			}
+
+			name := p.name()
+			if name == "" {
+				return nil, fmt.Errorf("expected property name at position %d", p.pos)
+			}
+			segments = append(segments, &childSegment{name: name})
+		case '[':
+			p.pos++
+			s, err := p.bracket()
+			if err != nil {
+				return nil, err
+			}
+			segments = append(segments, s)
+		default:
+			return segments, nil
+		}
+	}
+
+	return segments, nil
+}
+
+// descendant parses selector after .., e.g. ..name, ..* or ..[0]
+func (p *parser) descendant() (segment, error) {
+	switch p.peek() {
+	case '*':
+		p.pos++
+		return &wildcardSegment{}, nil
+	case '[':
+		p.pos++
+		return p.bracket()
+	}
+
+	name := p.name()
+	if name == "" {
+		return nil, fmt.Errorf("expected property name at position %d", p.pos)
+	}
+	return &childSegment{name: name}, nil
+}
+
+// consumes property name up to the next separator
+func (p *parser) name() string {
+	start := p.pos
+	for p.pos < len(p.input) && p.input[p.pos] != '.' && p.input[p.pos] != '[' {
+		if p.inFilter && strings.IndexByte(" \t\n)]=!<>&|,", p.input[p.pos]) >= 0 {
+			break
+		}
+		p.pos++
+	}
+	return p.input[start:p.pos]
+}
+
+// parses bracket selector, opening bracket is already consumed
+func (p *parser) bracket() (segment, error) {
+	p.skipSpaces()
+
+	if p.peek() == '?' {
+		p.pos++
+		start := p.pos
+		f, err := p.filter()
+		if err != nil {
+			return nil, err
+		}
+		text := strings.TrimSpace(p.input[start:p.pos])
+		if strings.HasPrefix(text, "(") && strings.HasSuffix(text, ")") {
+			text = text[1 : len(text)-1]
+		}
+
+		p.skipSpaces()
+		if p.peek() != ']' {
+			return nil, fmt.Errorf("expected ] at position %d", p.pos)
+		}
+		p.pos++
+		return &filterSegment{filter: f, text: text}, nil
+	}
+
+	selectors := make([]segment, 0)
+	for {
+		p.skipSpaces()
+		s, err := p.selector()
+		if err != nil {
+			return nil, err
+		}
+		selectors = append(selectors, s)
+
+		p.skipSpaces()
+		switch p.peek() {
+		case ',':
+			p.pos++
+			continue
+		case ']':
+			p.pos++
+		case 0:
+			return nil, fmt.Errorf("unterminated bracket at position %d", p.pos)
+		default:
+			return nil, fmt.Errorf("invalid selector at position %d", p.pos)
+		}
+		break
+	}
+
+	if len(selectors) == 1 {
+		return selectors[0], nil
+	}
+	return &unionSegment{selectors: selectors}, nil
+}
+
+// selector parses one selector of a bracket, e.g. *, 'name', 1 or 1:3
+func (p *parser) selector() (segment, error) {
+	c := p.peek()
+	switch {
+	case c == '*':
+		p.pos++
+		return &wildcardSegment{}, nil
+	case c == '\'' || c == '"':
+		name, err := p.quoted()
+		if err != nil {
+			return nil, err
+		}
+		return &childSegment{name: name}, nil
+	}
+
+	start, err := p.integer()
+	if err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+	if p.peek() != ':' {
+		if start == nil {
+			return nil, fmt.Errorf("invalid selector at position %d", p.pos)
+		}
+		return &indexSegment{index: *start}, nil
+	}
+
+	s := &sliceSegment{start: start, step: 1}
+	p.pos++
+	p.skipSpaces()
+	if s.end, err = p.integer(); err != nil {
+		return nil, err
+	}
+
+	p.skipSpaces()
+	if p.peek() == ':' {
+		p.pos++
+		p.skipSpaces()
+		step, err := p.integer()
+		if err != nil {
+			return nil, err
+		}
+		if step != nil {
+			s.step = *step
+		}
+	}
+	return s, nil
+}
+
+// integer parses optional integer, nil if there are no digits
+func (p *parser) integer() (*int, error) {
+	start := p.pos
+	if p.peek() == '-' {
+		p.pos++
+	}
+	for p.pos < len(p.input) && p.input[p.pos] >= '0' && p.input[p.pos] <= '9' {
+		p.pos++
+	}
+
+	if p.pos == start {
+		return nil, nil
+	}
+
+	i, err := strconv.Atoi(p.input[start:p.pos])
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %q at position %d", p.input[start:p.pos], start)
+	}
+	return &i, nil
+}
+
+// quoted parses single or double quoted string, backslash escapes the
+// next character
+func (p *parser) quoted() (string, error) {
+	quote := p.input[p.pos]
+	start := p.pos
+	p.pos++
+
+	var b strings.Builder
+	for p.pos < len(p.input) {
+		c := p.input[p.pos]
+		p.pos++
+		switch {
+		case c == quote:
+			return b.String(), nil
+		case c == '\\' && p.pos < len(p.input):
+			b.WriteByte(p.input[p.pos])
+			p.pos++
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return "", fmt.Errorf("unterminated string at position %d", start)
+}
+
+func (p *parser) peek() byte {
+	if p.pos < len(p.input) {
+		return p.input[p.pos]
+	}
+	return 0
+}
+
+func (p *parser) skipSpaces() {
+	for p.pos < len(p.input) && strings.IndexByte(" \t\n", p.input[p.pos]) >= 0 {
+		p.pos++
+	}
+}
+
+func clamp(i, min, max int) int {
+	if i < min {
+		return min
+	}
+	if i > max {
+		return max
+	}
+	return i
+}
+
+// appendChildren appends locations of array items, or object values
+// ordered by key
+func appendChildren(node interface{}, out []location) []location {
+	switch node := node.(type) {
+	case []interface{}:
+		for i, value := range node {
+			out = append(out, location{value, node, i})
+		}
+	case map[string]interface{}:
+		for _, key := range sortedKeys(node) {
+			out = append(out, location{node[key], node, key})
+		}
+	}
+	return out
+}
+
+// map iteration order is random, sort keys to make results stable
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
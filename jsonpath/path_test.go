@@ -0,0 +1,180 @@
+package jsonpath
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const document = `{
+	"data": {"list": ["a", "b", "c"]},
+	"items": [{"id": 1, "name": "x"}, {"id": 2, "name": "y"}],
+	"dotted key": "value",
+	"a]b": "bracket"
+}`
+
+func decode(t *testing.T, s string) interface{} {
+	var data interface{}
+	if err := json.Unmarshal([]byte(s), &data); err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestGet(t *testing.T) {
+	data := decode(t, document)
+
+	cases := []struct {
+		path  string
+		value interface{}
+		valid bool
+	}{
+		{"data.list[0]", "a", true},
+		{"$.data.list[1]", "b", true},
+		{"$.data.list[-1]", "c", true},
+		{"$['dotted key']", "value", true},
+		{"$['a]b']", "bracket", true},
+		{`$[ "a]b" ]`, "bracket", true},
+		{"$.items[1].name", "y", true},
+		{"$.items[*].id", []interface{}{1.0, 2.0}, true},
+		{"$.items.*.name", []interface{}{"x", "y"}, true},
+		{"$.data.list[5]", nil, false},
+		{"$.data.invalid_key", nil, false},
+		{"$.data.", nil, false},
+		{"$.data[abc]", nil, false},
+		{"$.data[0", nil, false},
+		{"$['a]b'", nil, false},
+		{"$['data", nil, false},
+	}
+
+	for _, c := range cases {
+		t.Logf("path: %s", c.path)
+		value, err := Get(data, c.path)
+		if c.valid {
+			assert.Nil(t, err)
+			assert.Equal(t, c.value, value)
+		} else {
+			assert.NotNil(t, err)
+		}
+	}
+}
+
+func TestDefinite(t *testing.T) {
+	assert.True(t, mustCompile(t, "$.a[0].b").Definite())
+	assert.False(t, mustCompile(t, "$.a[*].b").Definite())
+}
+
+func TestFindReturnsEmptyListWhenNothingMatches(t *testing.T) {
+	data := decode(t, document)
+	assert.Equal(t, []interface{}{}, mustCompile(t, "$.missing[*]").Find(data))
+}
+
+const store = `{
+	"store": {
+		"book": [
+			{"title": "Sayings", "author": "Rees", "price": 8.95, "tags": ["quotes"]},
+			{"title": "Sword", "author": "Waugh", "price": 12.99},
+			{"title": "Moby Dick", "author": "Melville", "price": 8.99, "isbn": "0-553"},
+			{"title": "The Lord", "author": "Tolkien", "price": 22.99, "isbn": "0-395"}
+		],
+		"bicycle": {"color": "red", "price": 19.95}
+	},
+	"limit": 10
+}`
+
+func TestFind(t *testing.T) {
+	data := decode(t, store)
+
+	cases := []struct {
+		path   string
+		values []interface{}
+	}{
+		{"$.store.book[1:3].author", []interface{}{"Waugh", "Melville"}},
+		{"$.store.book[:2].author", []interface{}{"Rees", "Waugh"}},
+		{"$.store.book[-2:].author", []interface{}{"Melville", "Tolkien"}},
+		{"$.store.book[::2].author", []interface{}{"Rees", "Melville"}},
+		{"$.store.book[::-1].author", []interface{}{"Tolkien", "Melville", "Waugh", "Rees"}},
+		{"$.store.book[5:].author", []interface{}{}},
+		{"$.store.book[0,3].author", []interface{}{"Rees", "Tolkien"}},
+		{"$.store.book[0]['title','author']", []interface{}{"Sayings", "Rees"}},
+		{"$..author", []interface{}{"Rees", "Waugh", "Melville", "Tolkien"}},
+		{"$.store..price", []interface{}{19.95, 8.95, 12.99, 8.99, 22.99}},
+		{"$..book[2].title", []interface{}{"Moby Dick"}},
+		{"$.store.book[?(@.isbn)].title", []interface{}{"Moby Dick", "The Lord"}},
+		{"$.store.book[?(!@.isbn)].title", []interface{}{"Sayings", "Sword"}},
+		{"$.store.book[?(@.price < 10)].title", []interface{}{"Sayings", "Moby Dick"}},
+		{"$.store.book[?@.price >= 12.99].title", []interface{}{"Sword", "The Lord"}},
+		{"$.store.book[?(@.price < $.limit && @.author != 'Rees')].title", []interface{}{"Moby Dick"}},
+		{"$.store.book[?(@.author == \"Rees\" || @.price > 20)].title", []interface{}{"Sayings", "The Lord"}},
+		{"$.store.book[?(@.tags[0] == 'quotes')].title", []interface{}{"Sayings"}},
+		{"$.store.book[?(@.author =~ /^m/i)].title", []interface{}{"Moby Dick"}},
+		{"$.store.book[?(@.author =~ 'll|ee')].title", []interface{}{"Sayings", "Moby Dick"}},
+		{"$.store.book[?(@.price > 'a')].title", []interface{}{}},
+		{"$.store[?(@.color == 'red')].price", []interface{}{19.95}},
+	}
+
+	for _, c := range cases {
+		t.Logf("path: %s", c.path)
+		assert.Equal(t, c.values, mustCompile(t, c.path).Find(data))
+	}
+}
+
+func TestRootArray(t *testing.T) {
+	data := decode(t, `[{"id": 1, "ok": true}, {"id": 2, "ok": false}, {"id": 3, "ok": true}]`)
+
+	value, err := Get(data, "$[1].id")
+	assert.Nil(t, err)
+	assert.Equal(t, 2.0, value)
+
+	value, err = Get(data, "$[?(@.ok == true)].id")
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{1.0, 3.0}, value)
+
+	value, err = Get(data, "$")
+	assert.Nil(t, err)
+	assert.Equal(t, data, value)
+}
+
+func TestCompileErrors(t *testing.T) {
+	invalid := []string{
+		"$..",
+		"$.a[?(@.b == )]",
+		"$.a[?(@.b == 1]",
+		"$.a[?(1)]",
+		"$.a[?(@.b =~ /[/)]",
+		"$.a['b]",
+		"$.a[1:2:x]",
+	}
+
+	for _, path := range invalid {
+		_, err := Compile(path)
+		assert.NotNil(t, err, path)
+	}
+}
+
+func TestDefiniteSelectors(t *testing.T) {
+	for _, path := range []string{"$.a[0:1]", "$.a[0,1]", "$..a", "$.a[?(@.b)]"} {
+		assert.False(t, mustCompile(t, path).Definite(), path)
+	}
+}
+
+func mustCompile(t *testing.T, path string) *Path {
+	p, err := Compile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return p
+}
+
+func TestReplace(t *testing.T) {
+	data := decode(t, `{"id": 7, "items": [{"id": 1, "at": "x"}, {"id": 2, "at": "y"}]}`)
+	ignored := func(interface{}) interface{} { return "<ignored>" }
+
+	data = mustCompile(t, "$..at").Replace(data, ignored)
+	data = mustCompile(t, "$.items[?(@.id > 1)].id").Replace(data, ignored)
+	assert.Equal(t, decode(t, `{"id": 7, "items": [{"id": 1, "at": "<ignored>"}, {"id": "<ignored>", "at": "<ignored>"}]}`), data)
+
+	// paths that select nothing leave data as it is
+	assert.Equal(t, data, mustCompile(t, "$.missing").Replace(data, ignored))
+	assert.Equal(t, "<ignored>", mustCompile(t, "$").Replace(data, ignored))
+}
@@ -0,0 +1,146 @@
+package markup
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"github.com/andybalholm/cascadia"
+	"github.com/antchfx/htmlquery"
+	"github.com/antchfx/xmlquery"
+	"github.com/antchfx/xpath"
+	"golang.org/x/net/html"
+)
+
+// Selector is a compiled XPath expression of an XML or HTML document, or
+// a CSS selector of an HTML document, e.g. //order[@status='paid']/id or
+// ul.orders > li
+//
+// Selected elements are converted to their text with surrounding white
+// space removed, attributes to their values. XPath functions like count()
+// or sum() select a single number, string or boolean.
+type Selector struct {
+	Text string
+	html bool
+	expr *xpath.Expr            // XPath expression, nil for CSS selectors
+	css  cascadia.SelectorGroup // CSS selector, HTML documents only
+}
+
+// CompileXML compiles XPath expression of an XML document
+func CompileXML(text string) (*Selector, error) {
+	expr, err := xpath.Compile(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid XPath expression %q: %s", text, err.Error())
+	}
+	return &Selector{Text: text, expr: expr}, nil
+}
+
+// CompileHTML compiles selector of an HTML document, selectors starting
+// with / or ( are XPath expressions, others are CSS selectors
+func CompileHTML(text string) (*Selector, error) {
+	if IsXPath(text) {
+		expr, err := xpath.Compile(text)
+		if err != nil {
+			return nil, fmt.Errorf("invalid XPath expression %q: %s", text, err.Error())
+		}
+		return &Selector{Text: text, html: true, expr: expr}, nil
+	}
+
+	css, err := cascadia.ParseGroup(text)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CSS selector %q: %s", text, err.Error())
+	}
+	return &Selector{Text: text, html: true, css: css}, nil
+}
+
+// Compile compiles selector of xml_body or html_body source
+func Compile(source string, text string) (*Selector, error) {
+	switch source {
+	case "xml_body":
+		return CompileXML(text)
+	case "html_body":
+		return CompileHTML(text)
+	}
+	return nil, fmt.Errorf("unsupported source %q", source)
+}
+
+// IsXPath reports whether HTML selector is an XPath expression
+func IsXPath(text string) bool {
+	text = strings.TrimSpace(text)
+	return strings.HasPrefix(text, "/") || strings.HasPrefix(text, "(")
+}
+
+// Select parses document and returns all selected values in document
+// order. If attribute is set, values of the attribute of selected
+// elements are returned instead of their text, elements without the
+// attribute are skipped.
+func (s *Selector) Select(document []byte, attribute string) ([]interface{}, error) {
+	if s.html {
+		root, err := htmlquery.Parse(bytes.NewReader(document))
+		if err != nil {
+			return nil, fmt.Errorf("unable to parse HTML body: %s", err.Error())
+		}
+
+		if s.expr == nil {
+			return selectCSS(root, s.css, attribute), nil
+		}
+		return selectXPath(htmlquery.CreateXPathNavigator(root), s.expr, attribute), nil
+	}
+
+	root, err := xmlquery.Parse(bytes.NewReader(document))
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse XML body: %s", err.Error())
+	}
+	return selectXPath(xmlquery.CreateXPathNavigator(root), s.expr, attribute), nil
+}
+
+func selectXPath(root xpath.NodeNavigator, expr *xpath.Expr, attribute string) []interface{} {
+	switch result := expr.Evaluate(root).(type) {
+	case *xpath.NodeIterator:
+		values := make([]interface{}, 0)
+		for result.MoveNext() {
+			node := result.Current()
+			if attribute == "" {
+				values = append(values, strings.TrimSpace(node.Value()))
+				continue
+			}
+
+			if value, ok := attributeValue(node.Copy(), attribute); ok {
+				values = append(values, value)
+			}
+		}
+		return values
+	case float64, string, bool:
+		return []interface{}{result}
+	}
+	return []interface{}{}
+}
+
+// attributeValue moves node to its attribute named name
+func attributeValue(node xpath.NodeNavigator, name string) (string, bool) {
+	if node.NodeType() != xpath.ElementNode {
+		return "", false
+	}
+
+	for node.MoveToNextAttribute() {
+		if node.LocalName() == name {
+			return node.Value(), true
+		}
+	}
+	return "", false
+}
+
+func selectCSS(root *html.Node, css cascadia.SelectorGroup, attribute string) []interface{} {
+	values := make([]interface{}, 0)
+	for _, node := range cascadia.QueryAll(root, css) {
+		if attribute == "" {
+			values = append(values, strings.TrimSpace(htmlquery.InnerText(node)))
+			continue
+		}
+
+		if htmlquery.ExistsAttr(node, attribute) {
+			values = append(values, htmlquery.SelectAttr(node, attribute))
+		}
+	}
+	return values
+}
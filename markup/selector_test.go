@@ -0,0 +1,111 @@
+package markup
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+const xmlDocument = `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <orders>
+      <order id="1" status="paid"><total>10.5</total></order>
+      <order id="2" status="pending"><total>4</total></order>
+      <order status="paid"><total> 7 </total></order>
+    </orders>
+  </soap:Body>
+</soap:Envelope>`
+
+const htmlDocument = `<!DOCTYPE html>
+<html>
+<head><title>Orders</title></head>
+<body>
+  <ul class="orders">
+    <li class="paid"><a href="/orders/1">Order 1</a></li>
+    <li class="pending"><a href="/orders/2">Order 2</a></li>
+    <li class="paid"><a>Order 3</a></li>
+  </ul>
+</body>
+</html>`
+
+func TestSelectXML(t *testing.T) {
+	cases := []struct {
+		selector  string
+		attribute string
+		values    []interface{}
+	}{
+		{"//order/total", "", []interface{}{"10.5", "4", "7"}},
+		{"//order[@status='paid']/@id", "", []interface{}{"1"}},
+		{"//order", "status", []interface{}{"paid", "pending", "paid"}},
+		{"//order", "id", []interface{}{"1", "2"}},
+		{"//soap:Body/orders/order[2]/total", "", []interface{}{"4"}},
+		{"count(//order)", "", []interface{}{3.0}},
+		{"sum(//order[@id]/total)", "", []interface{}{14.5}},
+		{"string(//order[1]/@status)", "", []interface{}{"paid"}},
+		{"boolean(//invoice)", "", []interface{}{false}},
+		{"//invoice", "", []interface{}{}},
+	}
+
+	for _, c := range cases {
+		t.Logf("selector: %s", c.selector)
+		selector, err := CompileXML(c.selector)
+		if !assert.Nil(t, err) {
+			continue
+		}
+
+		values, err := selector.Select([]byte(xmlDocument), c.attribute)
+		assert.Nil(t, err)
+		assert.Equal(t, c.values, values)
+	}
+}
+
+func TestSelectHTML(t *testing.T) {
+	cases := []struct {
+		selector  string
+		attribute string
+		values    []interface{}
+	}{
+		{"title", "", []interface{}{"Orders"}},
+		{"ul.orders > li.paid", "", []interface{}{"Order 1", "Order 3"}},
+		{"li a", "href", []interface{}{"/orders/1", "/orders/2"}},
+		{"li:nth-child(2)", "class", []interface{}{"pending"}},
+		{"//li[@class='paid']/a", "", []interface{}{"Order 1", "Order 3"}},
+		{"//a/@href", "", []interface{}{"/orders/1", "/orders/2"}},
+		{"(//li)[last()]", "", []interface{}{"Order 3"}},
+		{"table td", "", []interface{}{}},
+	}
+
+	for _, c := range cases {
+		t.Logf("selector: %s", c.selector)
+		selector, err := CompileHTML(c.selector)
+		if !assert.Nil(t, err) {
+			continue
+		}
+
+		values, err := selector.Select([]byte(htmlDocument), c.attribute)
+		assert.Nil(t, err)
+		assert.Equal(t, c.values, values)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	_, err := CompileXML("//order[")
+	assert.NotNil(t, err)
+
+	_, err = CompileHTML("ul >")
+	assert.NotNil(t, err)
+
+	_, err = CompileHTML("//li[")
+	assert.NotNil(t, err)
+
+	_, err = Compile("json_body", "$.a")
+	assert.NotNil(t, err)
+}
+
+func TestSelectInvalidXML(t *testing.T) {
+	selector, err := CompileXML("//order")
+	assert.Nil(t, err)
+
+	_, err = selector.Select([]byte("<orders><order></orders>"), "")
+	assert.NotNil(t, err)
+}
@@ -1,11 +1,17 @@
 package main
 
 import (
+	"compress/gzip"
+	"crypto/md5"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"log"
 	"net/http"
+	"regexp"
 	"sort"
+	"sync"
+	"time"
 )
 
 func JsonResponseHandler(w http.ResponseWriter, req *http.Request) {
@@ -37,11 +43,204 @@ func EchoHeadersHandler(w http.ResponseWriter, req *http.Request) {
 	}
 }
 
+func LoginHandler(w http.ResponseWriter, req *http.Request) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     "session",
+		Value:    "regression",
+		Path:     "/",
+		MaxAge:   3600,
+		HttpOnly: true,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func ProfileHandler(w http.ResponseWriter, req *http.Request) {
+	if _, err := req.Cookie("session"); err != nil {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	io.WriteString(w, "profile")
+}
+
+var flakyRequests = make(map[string]int)
+var flakyLock sync.Mutex
+
+// FlakyHandler fails the first request for every key
+func FlakyHandler(w http.ResponseWriter, req *http.Request) {
+	key := req.URL.Query().Get("key")
+
+	flakyLock.Lock()
+	flakyRequests[key]++
+	count := flakyRequests[key]
+	flakyLock.Unlock()
+
+	if count == 1 {
+		http.Error(w, "try again", http.StatusServiceUnavailable)
+		return
+	}
+	io.WriteString(w, "ok")
+}
+
+// JobHandler reports a job as done on the third request for every key
+func JobHandler(w http.ResponseWriter, req *http.Request) {
+	key := "job-" + req.URL.Query().Get("key")
+
+	flakyLock.Lock()
+	flakyRequests[key]++
+	count := flakyRequests[key]
+	flakyLock.Unlock()
+
+	if count < 3 {
+		io.WriteString(w, `{"status": "running"}`)
+		return
+	}
+	io.WriteString(w, `{"status": "done"}`)
+}
+
+func BasicAuthHandler(w http.ResponseWriter, req *http.Request) {
+	username, password, ok := req.BasicAuth()
+	if !ok || username != "regression" || password != "secret" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	io.WriteString(w, "authorized")
+}
+
+var digestParam = regexp.MustCompile(`(\w+)="?([^",]*)"?`)
+
+// DigestAuthHandler accepts user regression with password secret
+func DigestAuthHandler(w http.ResponseWriter, req *http.Request) {
+	const realm = "regressions"
+	const nonce = "0a4f113b"
+
+	h := func(text string) string {
+		return fmt.Sprintf("%x", md5.Sum([]byte(text)))
+	}
+
+	params := make(map[string]string)
+	for _, match := range digestParam.FindAllStringSubmatch(req.Header.Get("Authorization"), -1) {
+		params[match[1]] = match[2]
+	}
+
+	ha1 := h("regression:" + realm + ":secret")
+	ha2 := h(req.Method + ":" + params["uri"])
+	expected := h(ha1 + ":" + nonce + ":" + params["nc"] + ":" + params["cnonce"] + ":auth:" + ha2)
+	if params["username"] != "regression" || params["response"] != expected {
+		w.Header().Set("WWW-Authenticate", `Digest realm="`+realm+`", qop="auth", nonce="`+nonce+`"`)
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	io.WriteString(w, "authorized")
+}
+
+// TokenHandler issues tokens to client regression with secret secret
+func TokenHandler(w http.ResponseWriter, req *http.Request) {
+	username, password, ok := req.BasicAuth()
+	if !ok || username != "regression" || password != "secret" || req.PostFormValue("grant_type") != "client_credentials" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnauthorized)
+		io.WriteString(w, `{"error": "invalid_client"}`)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, `{"access_token": "regression-token", "token_type": "Bearer", "expires_in": 3600}`)
+}
+
+func BearerAuthHandler(w http.ResponseWriter, req *http.Request) {
+	if req.Header.Get("Authorization") != "Bearer regression-token" {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	io.WriteString(w, "authorized")
+}
+
+func GzipHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Encoding", "gzip")
+	writer := gzip.NewWriter(w)
+	io.WriteString(writer, "compressed")
+	writer.Close()
+}
+
+// OrdersHandler responds with a top-level JSON array
+func OrdersHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	io.WriteString(w, `[
+		{"id": 1, "status": "paid", "total": 20.5},
+		{"id": 2, "status": "pending", "total": 7},
+		{"id": 3, "status": "paid", "total": 12}
+	]`)
+}
+
+// SlowHandler responds after a delay, for response time assertions
+func SlowHandler(w http.ResponseWriter, req *http.Request) {
+	time.Sleep(50 * time.Millisecond)
+	io.WriteString(w, "slow")
+}
+
+// SoapOrdersHandler responds with a SOAP envelope, for xml_body assertions
+func SoapOrdersHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+	io.WriteString(w, `<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <GetOrdersResponse>
+      <order id="1" status="paid"><total>20.5</total></order>
+      <order id="2" status="pending"><total>7</total></order>
+      <order id="3" status="paid"><total>12</total></order>
+    </GetOrdersResponse>
+  </soap:Body>
+</soap:Envelope>`)
+}
+
+// OrdersPageHandler responds with an HTML page, for html_body assertions
+func OrdersPageHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	io.WriteString(w, `<!DOCTYPE html>
+<html>
+<head><title>Orders</title></head>
+<body>
+  <ul class="orders">
+    <li class="paid"><a href="/orders/1">Order 1</a></li>
+    <li class="pending"><a href="/orders/2">Order 2</a></li>
+    <li class="paid"><a href="/orders/3">Order 3</a></li>
+  </ul>
+  <form method="post" action="/orders">
+    <input type="hidden" name="csrf_token" value="c5f7a1">
+  </form>
+</body>
+</html>`)
+}
+
+// HeadersHandler responds with repeated headers and a content type with
+// parameters, for header assertions
+func HeadersHandler(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.Header().Add("Vary", "Accept")
+	w.Header().Add("Vary", "Origin")
+	w.Header().Set("X-Request-Id", "req-42")
+	io.WriteString(w, "{}")
+}
+
 func main() {
 	http.HandleFunc("/404", http.NotFound)
 	http.HandleFunc("/json-response", JsonResponseHandler)
 	http.HandleFunc("/echo-body", EchoHandler)
 	http.HandleFunc("/echo-headers", EchoHeadersHandler)
 	http.HandleFunc("/resource/555", EchoHandler)
+	http.HandleFunc("/login", LoginHandler)
+	http.HandleFunc("/profile", ProfileHandler)
+	http.HandleFunc("/flaky", FlakyHandler)
+	http.HandleFunc("/job", JobHandler)
+	http.HandleFunc("/gzip", GzipHandler)
+	http.HandleFunc("/basic-auth", BasicAuthHandler)
+	http.HandleFunc("/digest-auth", DigestAuthHandler)
+	http.HandleFunc("/oauth/token", TokenHandler)
+	http.HandleFunc("/bearer-auth", BearerAuthHandler)
+	http.HandleFunc("/orders", OrdersHandler)
+	http.HandleFunc("/slow", SlowHandler)
+	http.HandleFunc("/soap/orders", SoapOrdersHandler)
+	http.HandleFunc("/orders.html", OrdersPageHandler)
+	http.HandleFunc("/headers", HeadersHandler)
 	log.Fatal(http.ListenAndServe(":12345", nil))
 }
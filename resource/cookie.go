@@ -0,0 +1,78 @@
+package resource
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// cookie attributes available to cookie sources, `value` is the default
+var CookieAttributes = []string{
+	"value",
+	"domain",
+	"path",
+	"expires",    // RFC 3339 time, empty for session cookies
+	"expires_in", // seconds until the cookie expires
+	"max_age",
+	"http_only",
+	"secure",
+	"same_site", // Strict, Lax, None or empty
+}
+
+// FindCookie returns cookie set by the response, or nil if the response
+// doesn't set it. The last Set-Cookie header wins, like in browsers.
+func FindCookie(resp *http.Response, name string) *http.Cookie {
+	var found *http.Cookie
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == name {
+			found = cookie
+		}
+	}
+	return found
+}
+
+// CookieAttribute returns value of a cookie attribute.
+func CookieAttribute(cookie *http.Cookie, attribute string) (interface{}, error) {
+	switch attribute {
+	case "", "value":
+		return cookie.Value, nil
+	case "domain":
+		return cookie.Domain, nil
+	case "path":
+		return cookie.Path, nil
+	case "expires":
+		if cookie.Expires.IsZero() {
+			return "", nil
+		}
+		return cookie.Expires.UTC().Format(time.RFC3339), nil
+	case "expires_in":
+		// Max-Age has precedence over Expires
+		switch {
+		case cookie.MaxAge > 0:
+			return int64(cookie.MaxAge), nil
+		case cookie.MaxAge < 0:
+			return int64(0), nil
+		case !cookie.Expires.IsZero():
+			return int64(time.Until(cookie.Expires) / time.Second), nil
+		}
+		return nil, fmt.Errorf("cookie %q has no expiry", cookie.Name)
+	case "max_age":
+		return int64(cookie.MaxAge), nil
+	case "http_only":
+		return cookie.HttpOnly, nil
+	case "secure":
+		return cookie.Secure, nil
+	case "same_site":
+		switch cookie.SameSite {
+		case http.SameSiteStrictMode:
+			return "Strict", nil
+		case http.SameSiteLaxMode:
+			return "Lax", nil
+		case http.SameSiteNoneMode:
+			return "None", nil
+		}
+		return "", nil
+	}
+
+	return nil, fmt.Errorf("unknown cookie attribute %q", attribute)
+}
@@ -0,0 +1,63 @@
+package resource
+
+import (
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+func TestCookieAttributes(t *testing.T) {
+	resp := &http.Response{
+		Header: http.Header{
+			"Set-Cookie": []string{
+				"session=old",
+				"session=secret; Path=/; Domain=example.com; Max-Age=3600; HttpOnly; Secure; SameSite=Strict",
+				"theme=dark; Expires=Wed, 21 Oct 2015 07:28:00 GMT",
+			},
+		},
+	}
+
+	assert.Nil(t, FindCookie(resp, "missing"))
+
+	session := FindCookie(resp, "session")
+	cases := []struct {
+		attribute string
+		expected  interface{}
+	}{
+		{"", "secret"},
+		{"value", "secret"},
+		{"path", "/"},
+		{"domain", "example.com"},
+		{"expires", ""},
+		{"expires_in", int64(3600)},
+		{"max_age", int64(3600)},
+		{"http_only", true},
+		{"secure", true},
+		{"same_site", "Strict"},
+	}
+
+	for _, c := range cases {
+		value, err := CookieAttribute(session, c.attribute)
+		assert.Nil(t, err, c.attribute)
+		assert.Equal(t, c.expected, value, c.attribute)
+	}
+
+	theme := FindCookie(resp, "theme")
+	value, err := CookieAttribute(theme, "expires")
+	assert.Nil(t, err)
+	assert.Equal(t, "2015-10-21T07:28:00Z", value)
+
+	value, err = CookieAttribute(theme, "expires_in")
+	assert.Nil(t, err)
+	assert.True(t, value.(int64) < 0)
+
+	value, err = CookieAttribute(theme, "http_only")
+	assert.Nil(t, err)
+	assert.Equal(t, false, value)
+
+	_, err = CookieAttribute(FindCookie(&http.Response{Header: http.Header{"Set-Cookie": []string{"a=b"}}}, "a"), "expires_in")
+	assert.NotNil(t, err)
+
+	_, err = CookieAttribute(session, "color")
+	assert.NotNil(t, err)
+}
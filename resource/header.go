@@ -0,0 +1,31 @@
+package resource
+
+import (
+	"fmt"
+	"mime"
+	"strings"
+)
+
+// HeaderAttribute returns media type of a header value without parameters,
+// e.g. text/html for `text/html; charset=utf-8`, or value of a parameter,
+// e.g. charset. Media types and parameter names are case-insensitive, the
+// media type is returned in lowercase. Empty values have no attributes.
+func HeaderAttribute(value string, attribute string) (string, error) {
+	if attribute == "" {
+		return value, nil
+	}
+
+	if value == "" {
+		return "", nil
+	}
+
+	mediaType, params, err := mime.ParseMediaType(value)
+	if err != nil {
+		return "", fmt.Errorf("invalid media type %q: %s", value, err.Error())
+	}
+
+	if attribute == "media_type" {
+		return mediaType, nil
+	}
+	return params[strings.ToLower(attribute)], nil
+}
@@ -0,0 +1,31 @@
+package resource
+
+import (
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func TestHeaderAttribute(t *testing.T) {
+	cases := []struct {
+		value     string
+		attribute string
+		expected  string
+	}{
+		{"application/json; charset=utf-8", "", "application/json; charset=utf-8"},
+		{"application/json; charset=utf-8", "media_type", "application/json"},
+		{"Text/HTML;Charset=UTF-8", "media_type", "text/html"},
+		{"Text/HTML;Charset=UTF-8", "charset", "UTF-8"},
+		{`multipart/form-data; boundary="abc"`, "boundary", "abc"},
+		{"application/json", "charset", ""},
+		{"", "media_type", ""},
+	}
+
+	for _, c := range cases {
+		value, err := HeaderAttribute(c.value, c.attribute)
+		assert.Nil(t, err, c.value)
+		assert.Equal(t, c.expected, value, c.value)
+	}
+
+	_, err := HeaderAttribute("application/", "media_type")
+	assert.NotNil(t, err)
+}
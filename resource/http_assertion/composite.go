@@ -0,0 +1,130 @@
+package http_assertion
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/bluebookrun/bluebook/bcl"
+	"github.com/bluebookrun/bluebook/evaluator/proxy"
+	"github.com/bluebookrun/bluebook/resource"
+)
+
+// composite assertions combine other assertions evaluated against the
+// same response
+var CompositeKinds = []string{
+	"all_of", // every assertion passes
+	"any_of", // at least one assertion passes
+	"not",    // the assertion fails
+}
+
+// parseComposite reads list of assertion references of all_of and any_of,
+// or a single reference of not
+func (r *Resource) parseComposite(expression *bcl.ExpressionNode) error {
+	kind := string(expression.Field.Text)
+	if r.composite != "" {
+		return r.errorf("only one of `all_of`, `any_of` and `not` can be set")
+	}
+	r.composite = kind
+
+	if kind == "not" {
+		value, err := expression.ValueAsString()
+		if err != nil {
+			return err
+		}
+		r.assertions = append(r.assertions, &proxy.Proxy{
+			Ref:  value,
+			Type: proxy.ProxyAssertion,
+		})
+		return nil
+	}
+
+	listNode, err := expression.ValueAsList()
+	if err != nil {
+		return err
+	}
+	for _, node := range listNode.Nodes {
+		stringNode, ok := node.(*bcl.StringNode)
+		if !ok {
+			return fmt.Errorf("list item is not a string: %s", node)
+		}
+		r.assertions = append(r.assertions, &proxy.Proxy{
+			Ref:  string(stringNode.Text),
+			Type: proxy.ProxyAssertion,
+		})
+	}
+	return nil
+}
+
+func (r *Resource) validateComposite() error {
+	if r.source != "" || r.property != "" || r.comparison != "" || r.target != "" || r.targets != nil {
+		return r.errorf("%s assertion does not use `source`, `property`, `comparison` and `target`", r.composite)
+	}
+
+	if len(r.assertions) == 0 {
+		return r.errorf("`%s` requires at least one assertion", r.composite)
+	}
+	return nil
+}
+
+// link resolves assertions of composite assertion and of nested
+// composite assertions, parents are the enclosing composite assertions
+func (r *Resource) link(ctx *resource.ExecutionContext, parents []*Resource) error {
+	for _, parent := range parents {
+		if parent == r {
+			return fmt.Errorf("%s: circular reference to %s", parents[0].Node.Ref(), r.Node.Ref())
+		}
+	}
+	parents = append(parents, r)
+
+	for _, p := range r.assertions {
+		if err := p.Resolve(ctx); err != nil {
+			return fmt.Errorf("%s: %s", r.Node.Ref(), err.Error())
+		}
+
+		assertion, ok := p.Resource.(*Resource)
+		if !ok {
+			return fmt.Errorf("%s: `%s` item %s is not an http_assertion", r.Node.Ref(), r.composite, p.Ref)
+		}
+
+		if err := assertion.link(ctx, parents); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// assertComposite runs all assertions of composite assertion, failures
+// are explained as a tree with a line for every failed assertion
+func (r *Resource) assertComposite(ctx *resource.ExecutionContext) error {
+	failures := make([]string, 0)
+
+	for _, p := range r.assertions {
+		assertion := p.Resource.(*Resource)
+		err := assertion.Exec(ctx)
+
+		switch {
+		case r.composite == "not" && err == nil:
+			return r.errorf("not failed, %s passed", assertion.Node.Ref())
+		case r.composite == "not":
+			return nil
+		case r.composite == "any_of" && err == nil:
+			return nil
+		}
+
+		if err != nil {
+			// nested explanations are indented under the assertion
+			failures = append(failures, assertion.Node.Ref()+": "+strings.ReplaceAll(err.Error(), "\n", "\n    "))
+		}
+	}
+
+	switch r.composite {
+	case "all_of":
+		if len(failures) == 0 {
+			return nil
+		}
+		return r.errorf("all_of failed, %d of %d assertions failed:%s", len(failures), len(r.assertions), formatDiffs(failures))
+	case "any_of":
+		return r.errorf("any_of failed, none of %d assertions passed:%s", len(r.assertions), formatDiffs(failures))
+	}
+	return r.errorf("not implemented composite %q", r.composite)
+}
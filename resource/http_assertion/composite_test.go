@@ -0,0 +1,173 @@
+package http_assertion
+
+import (
+	"github.com/bluebookrun/bluebook/bcl"
+	"github.com/bluebookrun/bluebook/bcl/bcltest"
+	"github.com/bluebookrun/bluebook/resource"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+)
+
+const compositeAssertions = `
+resource "http_assertion" "status-200" {
+	source = "status_code"
+	comparison = "equals"
+	target = "200"
+}
+
+resource "http_assertion" "status-204" {
+	source = "status_code"
+	comparison = "equals"
+	target = "204"
+}
+
+resource "http_assertion" "body-empty" {
+	source = "body"
+	comparison = "is_empty"
+}
+
+resource "http_assertion" "success" {
+	any_of = [
+		"${http_assertion.status-200.id}",
+		"${http_assertion.status-204.id}",
+	]
+}
+
+resource "http_assertion" "not-no-content" {
+	not = "${http_assertion.status-204.id}"
+}
+
+resource "http_assertion" "empty-success" {
+	all_of = [
+		"${http_assertion.success.id}",
+		"${http_assertion.body-empty.id}",
+	]
+}
+`
+
+// linkAssertions creates and links all assertions of text
+func linkAssertions(t *testing.T, text string) *resource.ExecutionContext {
+	tree, err := bcl.Parse(text)
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	ctx := resource.NewExecutionContext()
+	for _, node := range tree.Root.Nodes {
+		block := node.(*bcl.BlockNode)
+		r, err := New(block)
+		if !assert.Nil(t, err, block.Ref()) {
+			t.FailNow()
+		}
+		assert.Nil(t, ctx.AddResource(block.Ref(), r))
+	}
+
+	for _, r := range ctx.ReferenceToResourceMap {
+		assert.Nil(t, r.Link(ctx))
+	}
+	return ctx
+}
+
+func responseContext(ctx *resource.ExecutionContext, statusCode int, body string) *resource.ExecutionContext {
+	ctx.CurrentResponse = &http.Response{StatusCode: statusCode}
+	ctx.CurrentResponseBody = []byte(body)
+	return ctx
+}
+
+func TestCompositeAssertions(t *testing.T) {
+	ctx := linkAssertions(t, compositeAssertions)
+
+	cases := []struct {
+		ref        string
+		statusCode int
+		body       string
+		valid      bool
+	}{
+		{"http_assertion.success", 200, "", true},
+		{"http_assertion.success", 204, "", true},
+		{"http_assertion.success", 500, "", false},
+		{"http_assertion.not-no-content", 200, "", true},
+		{"http_assertion.not-no-content", 204, "", false},
+		{"http_assertion.empty-success", 204, "", true},
+		{"http_assertion.empty-success", 200, "body", false},
+		{"http_assertion.empty-success", 404, "", false},
+	}
+
+	for _, c := range cases {
+		err := ctx.GetResourceByReference(c.ref).Exec(responseContext(ctx, c.statusCode, c.body))
+		if c.valid {
+			assert.Nil(t, err, c.ref)
+		} else {
+			assert.NotNil(t, err, c.ref)
+		}
+	}
+
+	err := ctx.GetResourceByReference("http_assertion.not-no-content").Exec(responseContext(ctx, 204, ""))
+	assert.EqualError(t, err, "not failed, http_assertion.status-204 passed")
+
+	err = ctx.GetResourceByReference("http_assertion.empty-success").Exec(responseContext(ctx, 500, "error"))
+	assert.EqualError(t, err, `all_of failed, 2 of 2 assertions failed:
+    - http_assertion.success: any_of failed, none of 2 assertions passed:
+        - http_assertion.status-200: equals comparison failed, 500 != 200
+        - http_assertion.status-204: equals comparison failed, 500 != 204
+    - http_assertion.body-empty: is_empty comparison failed, length 5`)
+}
+
+// otherResource is a resource that is not an assertion
+type otherResource struct {
+	id string
+}
+
+func (r *otherResource) Link(ctx *resource.ExecutionContext) error { return nil }
+func (r *otherResource) Exec(ctx *resource.ExecutionContext) error { return nil }
+func (r *otherResource) GetAttribute(name string) *string          { return &r.id }
+
+func TestCompositeLinking(t *testing.T) {
+	invalid := []string{
+		// missing reference
+		`resource "http_assertion" "a" { not = "${http_assertion.b.id}" }`,
+		// reference to a resource that is not an assertion
+		`resource "http_variable" "v" { source = "header", property = "Location", variable = "location" }
+		resource "http_assertion" "a" { all_of = ["${http_variable.v.id}"] }`,
+		// circular references
+		`resource "http_assertion" "a" { all_of = ["${http_assertion.a.id}"] }`,
+		`resource "http_assertion" "a" { any_of = ["${http_assertion.b.id}"] }
+		resource "http_assertion" "b" { not = "${http_assertion.a.id}" }`,
+	}
+
+	for _, text := range invalid {
+		tree, err := bcl.Parse(text)
+		if !assert.Nil(t, err, text) {
+			continue
+		}
+
+		ctx := resource.NewExecutionContext()
+		for _, node := range tree.Root.Nodes {
+			block := node.(*bcl.BlockNode)
+			var r resource.Resource = &otherResource{id: block.Ref()}
+			if string(block.Driver.Text) == "http_assertion" {
+				r, err = New(block)
+				assert.Nil(t, err, text)
+			}
+			assert.Nil(t, ctx.AddResource(block.Ref(), r))
+		}
+
+		err = ctx.GetResourceByReference("http_assertion.a").Link(ctx)
+		assert.NotNil(t, err, text)
+	}
+}
+
+func TestCompositeValidation(t *testing.T) {
+	invalid := []string{
+		`resource "http_assertion" "a" { all_of = [] }`,
+		`resource "http_assertion" "a" { all_of = ["${http_assertion.b.id}"], any_of = ["${http_assertion.b.id}"] }`,
+		`resource "http_assertion" "a" { not = "${http_assertion.b.id}", source = "status_code", comparison = "equals", target = "200" }`,
+		`resource "http_assertion" "a" { not = ["${http_assertion.b.id}"] }`,
+	}
+
+	for _, text := range invalid {
+		_, err := New(bcltest.Block(t, text))
+		assert.NotNil(t, err, text)
+	}
+}
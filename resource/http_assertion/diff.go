@@ -0,0 +1,200 @@
+package http_assertion
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/bluebookrun/bluebook/resource"
+)
+
+// differences reported in assertion errors, the rest are counted
+const maxDiffs = 20
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// jsonDiff appends differences between expected and actual JSON values,
+// every difference starts with JSON path of the value
+func jsonDiff(expected, actual interface{}, path string, diffs []string) []string {
+	switch expected := expected.(type) {
+	case map[string]interface{}:
+		actualMap, ok := actual.(map[string]interface{})
+		if !ok {
+			return append(diffs, changed(path, expected, actual))
+		}
+
+		keys := make([]string, 0, len(expected)+len(actualMap))
+		for key := range expected {
+			keys = append(keys, key)
+		}
+		for key := range actualMap {
+			if _, ok := expected[key]; !ok {
+				keys = append(keys, key)
+			}
+		}
+		sort.Strings(keys)
+
+		for _, key := range keys {
+			childPath := path + "." + key
+			if !identifierPattern.MatchString(key) {
+				childPath = fmt.Sprintf("%s[%s]", path, quoteJSON(key))
+			}
+
+			expectedValue, inExpected := expected[key]
+			actualValue, inActual := actualMap[key]
+			switch {
+			case !inActual:
+				diffs = append(diffs, fmt.Sprintf("%s: missing, expected %s", childPath, formatJSON(expectedValue)))
+			case !inExpected:
+				diffs = append(diffs, fmt.Sprintf("%s: unexpected %s", childPath, formatJSON(actualValue)))
+			default:
+				diffs = jsonDiff(expectedValue, actualValue, childPath, diffs)
+			}
+		}
+		return diffs
+	case []interface{}:
+		actualList, ok := actual.([]interface{})
+		if !ok {
+			return append(diffs, changed(path, expected, actual))
+		}
+
+		for i := 0; i < len(expected) || i < len(actualList); i++ {
+			childPath := fmt.Sprintf("%s[%d]", path, i)
+			switch {
+			case i >= len(actualList):
+				diffs = append(diffs, fmt.Sprintf("%s: missing, expected %s", childPath, formatJSON(expected[i])))
+			case i >= len(expected):
+				diffs = append(diffs, fmt.Sprintf("%s: unexpected %s", childPath, formatJSON(actualList[i])))
+			default:
+				diffs = jsonDiff(expected[i], actualList[i], childPath, diffs)
+			}
+		}
+		return diffs
+	}
+
+	if !scalarEqual(expected, actual) {
+		diffs = append(diffs, changed(path, expected, actual))
+	}
+	return diffs
+}
+
+// jsonContainsDiff appends differences that make expected JSON value
+// not a subset of actual. Objects may have extra keys, and every item
+// of expected arrays must be contained in some item of actual arrays.
+func jsonContainsDiff(expected, actual interface{}, path string, diffs []string) []string {
+	switch expected := expected.(type) {
+	case map[string]interface{}:
+		actualMap, ok := actual.(map[string]interface{})
+		if !ok {
+			return append(diffs, changed(path, expected, actual))
+		}
+
+		for _, key := range sortedKeys(expected) {
+			childPath := path + "." + key
+			if !identifierPattern.MatchString(key) {
+				childPath = fmt.Sprintf("%s[%s]", path, quoteJSON(key))
+			}
+
+			actualValue, ok := actualMap[key]
+			if !ok {
+				diffs = append(diffs, fmt.Sprintf("%s: missing, expected %s", childPath, formatJSON(expected[key])))
+				continue
+			}
+			diffs = jsonContainsDiff(expected[key], actualValue, childPath, diffs)
+		}
+		return diffs
+	case []interface{}:
+		actualList, ok := actual.([]interface{})
+		if !ok {
+			return append(diffs, changed(path, expected, actual))
+		}
+
+		for _, item := range expected {
+			found := false
+			for _, actualItem := range actualList {
+				if len(jsonContainsDiff(item, actualItem, path, nil)) == 0 {
+					found = true
+					break
+				}
+			}
+
+			if !found {
+				diffs = append(diffs, fmt.Sprintf("%s: no item contains %s", path, formatJSON(item)))
+			}
+		}
+		return diffs
+	}
+
+	if !scalarEqual(expected, actual) {
+		diffs = append(diffs, changed(path, expected, actual))
+	}
+	return diffs
+}
+
+// scalarEqual compares numbers by value, so 1 equals 1.0
+func scalarEqual(expected, actual interface{}) bool {
+	if resource.IsNumber(expected) && resource.IsNumber(actual) {
+		e, _ := resource.ToString(expected)
+		a, _ := resource.ToString(actual)
+		if e == a {
+			return true
+		}
+
+		ef, err := resource.ToFloat64(expected)
+		if err != nil {
+			return false
+		}
+		af, err := resource.ToFloat64(actual)
+		return err == nil && ef == af
+	}
+
+	switch actual.(type) {
+	case map[string]interface{}, []interface{}:
+		return false
+	}
+	return expected == actual
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func changed(path string, expected, actual interface{}) string {
+	return fmt.Sprintf("%s: expected %s, got %s", path, formatJSON(expected), formatJSON(actual))
+}
+
+// formatDiffs formats differences as a list, long lists are shortened
+func formatDiffs(diffs []string) string {
+	more := ""
+	if len(diffs) > maxDiffs {
+		more = fmt.Sprintf("\n    ... and %d more", len(diffs)-maxDiffs)
+		diffs = diffs[:maxDiffs]
+	}
+	return "\n    - " + strings.Join(diffs, "\n    - ") + more
+}
+
+// formatJSON formats value as compact JSON, long values are shortened
+func formatJSON(value interface{}) string {
+	s := quoteJSON(value)
+	if len(s) > 80 {
+		s = s[:77] + "..."
+	}
+	return s
+}
+
+func quoteJSON(value interface{}) string {
+	b := &strings.Builder{}
+	encoder := json.NewEncoder(b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(value); err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
@@ -0,0 +1,127 @@
+package http_assertion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bluebookrun/bluebook/bcl/bcltest"
+)
+
+func TestJSONDiff(t *testing.T) {
+	expected := map[string]interface{}{
+		"count": int64(1),
+		"big":   float64(2),
+		"list":  []interface{}{"a", "b"},
+		"odd key": map[string]interface{}{
+			"nested": true,
+		},
+		"gone": nil,
+	}
+	actual := map[string]interface{}{
+		"count":   float64(1),
+		"big":     int64(3),
+		"list":    []interface{}{"a"},
+		"odd key": "text",
+	}
+
+	assert.Equal(t, []string{
+		`$.big: expected 2, got 3`,
+		`$.gone: missing, expected null`,
+		`$.list[1]: missing, expected "b"`,
+		`$["odd key"]: expected {"nested":true}, got "text"`,
+	}, jsonDiff(expected, actual, "$", nil))
+
+	assert.Nil(t, jsonDiff(expected, expected, "$", nil))
+}
+
+func TestJSONContainsDiff(t *testing.T) {
+	actual := map[string]interface{}{
+		"id":    int64(1),
+		"name":  "a",
+		"items": []interface{}{map[string]interface{}{"id": int64(1), "tags": []interface{}{"x", "y"}}, map[string]interface{}{"id": int64(2)}},
+	}
+
+	assert.Nil(t, jsonContainsDiff(map[string]interface{}{}, actual, "$", nil))
+	assert.Nil(t, jsonContainsDiff(map[string]interface{}{
+		"id":    1.0,
+		"items": []interface{}{map[string]interface{}{"id": int64(2)}, map[string]interface{}{"tags": []interface{}{"y"}}},
+	}, actual, "$", nil))
+
+	assert.Equal(t, []string{
+		`$.items: no item contains {"id":3}`,
+		`$.missing: missing, expected true`,
+		`$.name: expected "b", got "a"`,
+	}, jsonContainsDiff(map[string]interface{}{
+		"name":    "b",
+		"missing": true,
+		"items":   []interface{}{map[string]interface{}{"id": int64(3)}},
+	}, actual, "$", nil))
+}
+
+func TestJSONDocumentComparisons(t *testing.T) {
+	body := `{"data": {"id": 1, "tags": ["a", "b"], "price": 10.50}, "total": 1}`
+
+	cases := []struct {
+		property   string
+		comparison string
+		target     string
+		valid      bool
+	}{
+		{"", "json_equals", `{"total": 1.0, "data": {"tags": ["a", "b"], "price": 10.5, "id": 1}}`, true},
+		{"", "json_equals", `{"total": 1}`, false},
+		{"data.tags", "json_equals", `["a", "b"]`, true},
+		{"data.tags", "json_equals", `["b", "a"]`, false},
+		{"", "json_contains", `{"data": {"tags": ["b"]}}`, true},
+		{"", "json_contains", `{"data": {"tags": ["c"]}}`, false},
+		{"data", "json_contains", `{"id": "${var.id}"}`, false},
+		{"data", "json_contains", `{"id": ${var.id}}`, true},
+	}
+
+	for _, c := range cases {
+		r, err := New(bcltest.Block(t, `resource "http_assertion" "a" {
+			source = "json_body"
+			property = "`+c.property+`"
+			comparison = "`+c.comparison+`"
+			target = <<<EOF
+`+c.target+`
+EOF
+		}`))
+		if !assert.Nil(t, err, c.target) {
+			continue
+		}
+
+		ctx := bodyContext(body)
+		ctx.SetVariable("id", "1")
+		err = r.Exec(ctx)
+		if c.valid {
+			assert.Nil(t, err, c.target)
+		} else {
+			assert.NotNil(t, err, c.target)
+		}
+	}
+
+	r, err := New(bcltest.Block(t, `resource "http_assertion" "a" {
+		source = "json_body"
+		property = "data"
+		comparison = "json_equals"
+		target = <<<EOF
+{"id": 2, "tags": ["a"], "price": 10.5}
+EOF
+	}`))
+	assert.Nil(t, err)
+	assert.EqualError(t, r.Exec(bodyContext(body)), `json_equals comparison failed, 2 differences:
+    - data.id: expected 2, got 1
+    - data.tags[1]: unexpected "b"`)
+
+	invalid := []string{
+		`resource "http_assertion" "a" { source = "json_body", comparison = "json_equals" }`,
+		`resource "http_assertion" "a" { source = "json_body", comparison = "json_equals", target = "{" }`,
+		`resource "http_assertion" "a" { source = "body", comparison = "json_contains", target = "{}" }`,
+	}
+
+	for _, text := range invalid {
+		_, err := New(bcltest.Block(t, text))
+		assert.NotNil(t, err, text)
+	}
+}
@@ -1,15 +1,21 @@
 package http_assertion
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/bluebookrun/bluebook/bcl"
+	"github.com/bluebookrun/bluebook/evaluator/proxy"
 	"github.com/bluebookrun/bluebook/interpolator"
+	"github.com/bluebookrun/bluebook/jsonpath"
+	"github.com/bluebookrun/bluebook/markup"
 	"github.com/bluebookrun/bluebook/resource"
-	"github.com/firewut/go-json-map"
 	"github.com/google/uuid"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"net/url"
+	"regexp"
 	"strconv"
 	"strings"
+	"time"
+	"unicode/utf8"
 )
 
 type Resource struct {
@@ -18,8 +24,22 @@ type Resource struct {
 
 	source     string
 	property   string
+	attribute  string // cookie attribute, element attribute of xml_body and html_body sources, or media type or parameter of header
 	comparison string
 	target     string
+	targets    []string         // target list, one_of comparison only
+	capture    bool             // store named groups of matches comparison as variables
+	schemaFile string           // path to JSON schema, matches_schema comparison only
+	match      string           // how values selected by JSON path or selector are compared
+	snapshot   string           // snapshot name, matches_snapshot comparison only, derived from test and step if empty
+	ignore     []*jsonpath.Path // values replaced in snapshots, matches_snapshot comparison only
+	composite  string           // all_of, any_of or not, composite assertions only
+	assertions []*proxy.Proxy   // assertions combined by composite assertion
+
+	path     *jsonpath.Path     // compiled property of json_body source, nil if interpolated
+	selector *markup.Selector   // compiled property of xml_body and html_body sources, nil if interpolated
+	pattern  *regexp.Regexp     // compiled target of match comparisons, nil if interpolated
+	schema   *jsonschema.Schema // compiled schema of matches_schema comparison, nil if interpolated
 }
 
 var ComparisonsRequiringTarget = []string{
@@ -32,11 +52,89 @@ var ComparisonsRequiringTarget = []string{
 	"greater_than",
 	"greater_than_or_equal",
 	"equals_number",
+	"matches",
+	"does_not_match",
+	"json_equals",
+	"json_contains",
+	"length_equals",
+	"length_greater_than",
+	"length_less_than",
+}
+
+// comparisons with a regular expression target
+var MatchComparisons = []string{
+	"matches",
+	"does_not_match",
+}
+
+// how json_body, xml_body and html_body assertions compare values
+// selected by JSON path or selector
+var MatchModes = []string{
+	"all",   // every value passes the comparison, default for paths with wildcards, slices or filters
+	"any",   // at least one value passes the comparison
+	"count", // number of selected values is compared with target
+}
+
+// how header assertions compare values of repeated headers, the first
+// value is compared if match is not set
+var HeaderMatchModes = []string{
+	"all_values", // every value of the header passes the comparison
+	"any_value",  // at least one value of the header passes the comparison
+	"count",      // number of header values is compared with target
+}
+
+var CountComparisons = []string{
+	"equals",
+	"does_not_equal",
+	"less_than",
+	"less_than_or_equal",
+	"greater_than",
+	"greater_than_or_equal",
+}
+
+// comparisons of json_body source that use the whole body if property
+// is not set
+var WholeBodyComparisons = []string{
+	"matches_schema",
+	"matches_snapshot",
+	"json_equals",
+	"json_contains",
+}
+
+// comparisons with a JSON document target
+var JSONDocumentComparisons = []string{
+	"json_equals",
+	"json_contains",
+}
+
+// comparisons of JSON value type
+var TypeComparisons = []string{
+	"is_string",
+	"is_bool",
+	"is_array",
+	"is_object",
+}
+
+// comparisons of number of characters of strings, items of arrays or
+// keys of objects, target is an integer
+var LengthComparisons = []string{
+	"length_equals",
+	"length_greater_than",
+	"length_less_than",
 }
 
 var SourceRequiringProperty = []string{
 	"json_body",
+	"xml_body",
+	"html_body",
 	"header",
+	"cookie",
+}
+
+// sources with XPath or CSS selector property
+var MarkupSources = []string{
+	"xml_body",
+	"html_body",
 }
 
 var JSONBodyComparisons = []string{
@@ -55,6 +153,43 @@ var JSONBodyComparisons = []string{
 	"equals_number", // use json.Number
 	"is_null",
 	"is_a_number",
+	"matches",
+	"does_not_match",
+	"matches_schema",   // JSON schema in target or schema_file
+	"matches_snapshot", // structural comparison with stored snapshot
+	"json_equals",      // same JSON document, ignoring key order and number formatting
+	"json_contains",    // target JSON document is a subset of the value
+	"is_string",
+	"is_bool",
+	"is_array",
+	"is_object",
+	"length_equals",
+	"length_greater_than",
+	"length_less_than",
+	"one_of", // value equals one of target list items
+}
+
+// comparisons of xml_body and html_body sources, values are text of
+// selected elements or attributes, or results of XPath functions
+var MarkupComparisons = []string{
+	"equals",
+	"does_not_equal",
+	"less_than",
+	"less_than_or_equal",
+	"greater_than",
+	"greater_than_or_equal",
+	"contains",
+	"does_not_contain",
+	"is_empty",
+	"is_not_empty",
+	"equals_number",
+	"is_a_number",
+	"matches",
+	"does_not_match",
+	"length_equals",
+	"length_greater_than",
+	"length_less_than",
+	"one_of",
 }
 
 var StatusCodeComparisons = []string{
@@ -73,15 +208,90 @@ var BodyComparisons = []string{
 	"does_not_equal",
 	"contains",
 	"does_not_contain",
+	"matches",
+	"does_not_match",
+	"matches_snapshot",
+	"length_equals",
+	"length_greater_than",
+	"length_less_than",
+	"one_of",
 }
 
 var HeaderComparisons = []string{
+	"header_exists", // header is set, even if empty
+	"header_absent", // header is not set
+	"is_empty",
+	"is_not_empty",
+	"equals",
+	"does_not_equal",
+	"contains",
+	"does_not_contain",
+	"matches",
+	"does_not_match",
+	"length_equals",
+	"length_greater_than",
+	"length_less_than",
+	"one_of",
+}
+
+var URLComparisons = []string{
+	"is_empty",
+	"is_not_empty",
+	"equals",
+	"does_not_equal",
+	"contains",
+	"does_not_contain",
+}
+
+var BodySizeComparisons = []string{
+	"equals",
+	"does_not_equal",
+	"less_than",
+	"less_than_or_equal",
+	"greater_than",
+	"greater_than_or_equal",
+}
+
+var BodyHashComparisons = []string{
+	"equals",
+	"does_not_equal",
+}
+
+var ContentEncodingComparisons = []string{
+	"is_empty",
+	"is_not_empty",
+	"equals",
+	"does_not_equal",
+	"contains",
+	"does_not_contain",
+}
+
+// target of response_time source is a duration, e.g. 300ms
+var ResponseTimeComparisons = []string{
+	"less_than",
+	"less_than_or_equal",
+	"greater_than",
+	"greater_than_or_equal",
+}
+
+// hash algorithms of body_hash source, sha256 is the default
+var BodyHashAlgorithms = []string{
+	"md5",
+	"sha1",
+	"sha256",
+}
+
+var CookieComparisons = []string{
 	"is_empty",
 	"is_not_empty",
 	"equals",
 	"does_not_equal",
 	"contains",
 	"does_not_contain",
+	"less_than",
+	"less_than_or_equal",
+	"greater_than",
+	"greater_than_or_equal",
 }
 
 func New(node *bcl.BlockNode) (*Resource, error) {
@@ -92,6 +302,10 @@ func New(node *bcl.BlockNode) (*Resource, error) {
 		},
 	}
 
+	if len(node.Blocks) != 0 {
+		return nil, fmt.Errorf("unknown block %q", node.Blocks[0].Id.Text)
+	}
+
 	for _, expression := range node.Expressions {
 		switch {
 		case string(expression.Field.Text) == "source":
@@ -106,6 +320,12 @@ func New(node *bcl.BlockNode) (*Resource, error) {
 				return nil, err
 			}
 			r.property = value
+		case string(expression.Field.Text) == "attribute":
+			value, err := expression.ValueAsString()
+			if err != nil {
+				return nil, err
+			}
+			r.attribute = value
 		case string(expression.Field.Text) == "comparison":
 			value, err := expression.ValueAsString()
 			if err != nil {
@@ -113,11 +333,70 @@ func New(node *bcl.BlockNode) (*Resource, error) {
 			}
 			r.comparison = value
 		case string(expression.Field.Text) == "target":
+			switch value := expression.Value.(type) {
+			case *bcl.StringNode:
+				r.target = string(value.Text)
+			case *bcl.ListNode:
+				r.targets = make([]string, 0)
+				for _, node := range value.Nodes {
+					stringNode, ok := node.(*bcl.StringNode)
+					if !ok {
+						return nil, fmt.Errorf("list item is not a string: %s", node)
+					}
+					r.targets = append(r.targets, string(stringNode.Text))
+				}
+			default:
+				return nil, fmt.Errorf("`target` must be a string or a list of strings")
+			}
+		case string(expression.Field.Text) == "capture":
+			value, err := expression.ValueAsString()
+			if err != nil {
+				return nil, err
+			}
+			capture, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid `capture` value %q", value)
+			}
+			r.capture = capture
+		case string(expression.Field.Text) == "schema_file":
+			value, err := expression.ValueAsString()
+			if err != nil {
+				return nil, err
+			}
+			r.schemaFile = value
+		case string(expression.Field.Text) == "match":
 			value, err := expression.ValueAsString()
 			if err != nil {
 				return nil, err
 			}
-			r.target = value
+			r.match = value
+		case string(expression.Field.Text) == "snapshot":
+			value, err := expression.ValueAsString()
+			if err != nil {
+				return nil, err
+			}
+			r.snapshot = value
+		case string(expression.Field.Text) == "ignore":
+			listNode, err := expression.ValueAsList()
+			if err != nil {
+				return nil, err
+			}
+			for _, node := range listNode.Nodes {
+				stringNode, ok := node.(*bcl.StringNode)
+				if !ok {
+					return nil, fmt.Errorf("list item is not a string: %s", node)
+				}
+
+				path, err := jsonpath.Compile(string(stringNode.Text))
+				if err != nil {
+					return nil, fmt.Errorf("invalid `ignore` value: %s", err.Error())
+				}
+				r.ignore = append(r.ignore, path)
+			}
+		case stringInSlice(string(expression.Field.Text), CompositeKinds):
+			if err := r.parseComposite(expression); err != nil {
+				return nil, err
+			}
 		}
 	}
 
@@ -129,7 +408,12 @@ func New(node *bcl.BlockNode) (*Resource, error) {
 }
 
 func (r *Resource) validate() error {
-	if r.property == "" && stringInSlice(r.source, SourceRequiringProperty) {
+	if r.composite != "" {
+		return r.validateComposite()
+	}
+
+	// schemas and snapshots can compare the whole body
+	if r.property == "" && stringInSlice(r.source, SourceRequiringProperty) && !stringInSlice(r.comparison, WholeBodyComparisons) {
 		return r.errorf("missing `property`")
 	}
 
@@ -138,16 +422,51 @@ func (r *Resource) validate() error {
 	switch r.source {
 	case "json_body":
 		validComparisons = JSONBodyComparisons
+	case "xml_body", "html_body":
+		validComparisons = MarkupComparisons
 	case "status_code":
 		validComparisons = StatusCodeComparisons
 	case "body":
 		validComparisons = BodyComparisons
 	case "header":
 		validComparisons = HeaderComparisons
+	case "cookie":
+		validComparisons = CookieComparisons
+	case "url":
+		validComparisons = URLComparisons
+	case "body_size":
+		validComparisons = BodySizeComparisons
+	case "body_hash":
+		validComparisons = BodyHashComparisons
+		if r.property != "" && !stringInSlice(r.property, BodyHashAlgorithms) {
+			return r.errorf("invalid `property` value %q, allowed values are %s",
+				r.property, strings.Join(BodyHashAlgorithms, ", "))
+		}
+	case "content_encoding":
+		validComparisons = ContentEncodingComparisons
+	case "response_time":
+		validComparisons = ResponseTimeComparisons
+		if r.property != "" && !stringInSlice(r.property, resource.TimingPhases) {
+			return r.errorf("invalid `property` value %q, allowed values are %s",
+				r.property, strings.Join(resource.TimingPhases, ", "))
+		}
+
+		if _, err := time.ParseDuration(r.target); err != nil && !strings.Contains(r.target, "${") {
+			return r.errorf("invalid `target` duration %q", r.target)
+		}
 	default:
 		return r.errorf("invalid `source` value %q", r.source)
 	}
 
+	if r.attribute != "" {
+		if r.source != "cookie" && r.source != "header" && !stringInSlice(r.source, MarkupSources) {
+			return r.errorf("`attribute` is supported by cookie, header, xml_body and html_body sources only")
+		}
+		if r.source == "cookie" && !stringInSlice(r.attribute, resource.CookieAttributes) {
+			return r.errorf("invalid `attribute` value %q", r.attribute)
+		}
+	}
+
 	if !stringInSlice(r.comparison, validComparisons) {
 		return r.errorf("invalid `comparison` value %q", r.comparison)
 	}
@@ -156,6 +475,138 @@ func (r *Resource) validate() error {
 		return r.errorf("invalid `target` value %q", r.target)
 	}
 
+	if r.comparison == "one_of" && len(r.targets) == 0 {
+		return r.errorf("one_of comparison requires a list `target`, e.g. [\"a\", \"b\"]")
+	} else if r.comparison != "one_of" && r.targets != nil {
+		return r.errorf("list `target` is supported by one_of comparison only")
+	}
+
+	if stringInSlice(r.comparison, LengthComparisons) && !strings.Contains(r.target, "${") {
+		if _, err := strconv.Atoi(r.target); err != nil {
+			return r.errorf("invalid `target` length %q", r.target)
+		}
+	}
+
+	if r.comparison == "matches_schema" {
+		if err := r.validateSchema(); err != nil {
+			return err
+		}
+	} else if r.schemaFile != "" {
+		return r.errorf("`schema_file` is supported by matches_schema comparison only")
+	}
+
+	if r.comparison == "matches_snapshot" {
+		if err := r.validateSnapshot(); err != nil {
+			return err
+		}
+	} else if r.snapshot != "" || r.ignore != nil {
+		return r.errorf("`snapshot` and `ignore` are supported by matches_snapshot comparison only")
+	}
+
+	if r.match != "" {
+		if err := r.validateMatch(); err != nil {
+			return err
+		}
+	}
+
+	if stringInSlice(r.comparison, JSONDocumentComparisons) && !strings.Contains(r.target, "${") {
+		if _, err := resource.DecodeJSON([]byte(r.target)); err != nil {
+			return r.errorf("invalid `target` JSON document: %s", err.Error())
+		}
+	}
+
+	// interpolated paths are compiled when the assertion runs, empty
+	// path selects the whole body
+	if r.source == "json_body" && !strings.Contains(r.property, "${") {
+		path, err := jsonpath.Compile(r.property)
+		if err != nil {
+			return r.errorf("invalid `property` value: %s", err.Error())
+		}
+		r.path = path
+	}
+
+	if stringInSlice(r.source, MarkupSources) && !strings.Contains(r.property, "${") {
+		selector, err := markup.Compile(r.source, r.property)
+		if err != nil {
+			return r.errorf("invalid `property` value: %s", err.Error())
+		}
+		r.selector = selector
+	}
+
+	if r.capture && r.comparison != "matches" {
+		return r.errorf("`capture` is supported by matches comparison only")
+	}
+
+	// interpolated expressions are compiled when the assertion runs
+	if stringInSlice(r.comparison, MatchComparisons) && !strings.Contains(r.target, "${") {
+		pattern, err := regexp.Compile(r.target)
+		if err != nil {
+			return r.errorf("invalid `target` regular expression: %s", err.Error())
+		}
+
+		if r.capture && !hasNamedGroups(pattern) {
+			return r.errorf("`capture` requires named groups in `target`, e.g. (?P<id>\\d+)")
+		}
+		r.pattern = pattern
+	}
+
+	return nil
+}
+
+func (r *Resource) validateMatch() error {
+	if r.source != "json_body" && r.source != "header" && !stringInSlice(r.source, MarkupSources) {
+		return r.errorf("`match` is supported by json_body, header, xml_body and html_body sources only")
+	}
+
+	modes := MatchModes
+	if r.source == "header" {
+		modes = HeaderMatchModes
+	}
+
+	if !stringInSlice(r.match, modes) {
+		return r.errorf("invalid `match` value %q, allowed values are %s",
+			r.match, strings.Join(modes, ", "))
+	}
+
+	switch r.comparison {
+	case "matches_schema", "matches_snapshot", "header_exists", "header_absent":
+		return r.errorf("`match` is not supported by %s comparison", r.comparison)
+	}
+
+	if r.match == "count" && !stringInSlice(r.comparison, CountComparisons) {
+		return r.errorf("invalid `comparison` value %q for count match, allowed values are %s",
+			r.comparison, strings.Join(CountComparisons, ", "))
+	}
+	return nil
+}
+
+func (r *Resource) validateSnapshot() error {
+	if r.target != "" {
+		return r.errorf("matches_snapshot comparison does not use `target`")
+	}
+
+	if r.ignore != nil && r.source != "json_body" {
+		return r.errorf("`ignore` is supported by json_body source only")
+	}
+	return nil
+}
+
+// validateSchema compiles schema of matches_schema comparison, schemas
+// with interpolated target or path are compiled when the assertion runs
+func (r *Resource) validateSchema() error {
+	if (r.target == "") == (r.schemaFile == "") {
+		return r.errorf("matches_schema comparison requires one of `target` and `schema_file`")
+	}
+
+	if strings.Contains(r.target, "${") || strings.Contains(r.schemaFile, "${") {
+		return nil
+	}
+
+	schema, err := compileSchema(r.target, r.schemaFile)
+	if err != nil {
+		return r.errorf("invalid JSON schema: %s", err.Error())
+	}
+	r.schema = schema
 	return nil
 }
 
@@ -168,10 +619,14 @@ func (r *Resource) GetAttribute(name string) *string {
 }
 
 func (r *Resource) Link(ctx *resource.ExecutionContext) error {
-	return nil
+	return r.link(ctx, nil)
 }
 
 func (r *Resource) Exec(ctx *resource.ExecutionContext) error {
+	if r.composite != "" {
+		return r.assertComposite(ctx)
+	}
+
 	switch r.source {
 	case "status_code":
 		return r.assertStatusCode(ctx)
@@ -181,6 +636,20 @@ func (r *Resource) Exec(ctx *resource.ExecutionContext) error {
 		return r.assertHeader(ctx)
 	case "json_body":
 		return r.assertJSONBody(ctx)
+	case "xml_body", "html_body":
+		return r.assertMarkupBody(ctx)
+	case "cookie":
+		return r.assertCookie(ctx)
+	case "url":
+		return r.assertURL(ctx)
+	case "body_size":
+		return r.assertBodySize(ctx)
+	case "body_hash":
+		return r.assertBodyHash(ctx)
+	case "content_encoding":
+		return r.assertContentEncoding(ctx)
+	case "response_time":
+		return r.assertResponseTime(ctx)
 	default:
 		return r.errorf("not implemented source %q", r.source)
 	}
@@ -255,9 +724,14 @@ func (r *Resource) assertStatusCode(ctx *resource.ExecutionContext) error {
 }
 
 func (r *Resource) assertJSONBody(ctx *resource.ExecutionContext) error {
-	var jsonData map[string]interface{}
+	switch r.comparison {
+	case "matches_schema":
+		return r.assertSchema(ctx)
+	case "matches_snapshot":
+		return r.assertSnapshot(ctx)
+	}
 
-	path, err := interpolator.Eval(r.property, ctx)
+	path, err := r.jsonPath(ctx)
 	if err != nil {
 		return err
 	}
@@ -267,81 +741,188 @@ func (r *Resource) assertJSONBody(ctx *resource.ExecutionContext) error {
 		return err
 	}
 
-	err = json.Unmarshal(ctx.CurrentResponseBody, &jsonData)
+	document, err := resource.DecodeJSON(ctx.CurrentResponseBody)
 	if err != nil {
 		return r.errorf("unable to decode JSON body: %s", err.Error())
 	}
 
-	property, err := gjm.GetProperty(jsonData, path)
+	match := r.match
+	if match == "" {
+		if path.Definite() {
+			property, err := path.Get(document)
+			if err != nil {
+				return err
+			}
+			return r.compareJSON(ctx, property, target)
+		}
+		match = "all"
+	}
+
+	return r.assertValues(ctx, path.Find(document), match, "JSON path", path.Text, target)
+}
+
+// assertMarkupBody compares values selected from XML or HTML body, every
+// selected value has to pass the comparison unless match is set
+func (r *Resource) assertMarkupBody(ctx *resource.ExecutionContext) error {
+	selector, err := r.markupSelector(ctx)
 	if err != nil {
 		return err
 	}
 
-	switch r.comparison {
-	case "equals":
-		value, err := castJSONPropertyToString(property)
-		if err != nil {
-			return err
-		}
-		if value != target {
-			return r.errorf("equals comparison failed, %s != %s", value, target)
-		}
-	case "does_not_equal":
-		value, err := castJSONPropertyToString(property)
-		if err != nil {
-			return err
-		}
-		if value == target {
-			return r.errorf("does_not_equals comparison failed, %s == %s", value, target)
-		}
-	case "less_than":
-		value, err := castJSONPropertyToNumber(property)
-		if err != nil {
-			return err
-		}
-		targetFloat, err := strconv.ParseFloat(target, 64)
-		if err != nil {
-			return err
+	target, err := interpolator.Eval(r.target, ctx)
+	if err != nil {
+		return err
+	}
+
+	values, err := selector.Select(ctx.CurrentResponseBody, r.attribute)
+	if err != nil {
+		return r.errorf("%s", err.Error())
+	}
+
+	match := r.match
+	if match == "" {
+		if len(values) == 1 {
+			return r.compareJSON(ctx, values[0], target)
 		}
-		if value >= targetFloat {
-			return r.errorf("less_than comparison failed, %f >= %f", value, targetFloat)
+		match = "all"
+	}
+
+	return r.assertValues(ctx, values, match, "selector", selector.Text, target)
+}
+
+// assertValues compares values selected by text, a JSON path, selector or
+// header name, according to match mode
+func (r *Resource) assertValues(ctx *resource.ExecutionContext, values []interface{}, match string, kind string, text string, target string) error {
+	switch match {
+	case "count":
+		count := len(values)
+		switch r.comparison {
+		case "equals", "does_not_equal":
+			return r.assertText(strconv.Itoa(count), target)
 		}
-	case "less_than_or_equal":
-		value, err := castJSONPropertyToNumber(property)
-		if err != nil {
-			return err
+		return r.assertNumber(float64(count), target)
+	case "any":
+		if len(values) == 0 {
+			return r.errorf("%s comparison failed, %s %q selected no values", r.comparison, kind, text)
 		}
-		targetFloat, err := strconv.ParseFloat(target, 64)
-		if err != nil {
-			return err
+
+		var err error
+		for _, value := range values {
+			if err = r.compareValue(ctx, value, target); err == nil {
+				return nil
+			}
 		}
-		if value > targetFloat {
-			return r.errorf("less_than_or_equal comparison failed, %f > %f", value, targetFloat)
+		return r.errorf("none of %d values at %q passed: %s", len(values), text, err.Error())
+	case "all":
+		if len(values) == 0 {
+			return r.errorf("%s comparison failed, %s %q selected no values", r.comparison, kind, text)
 		}
-	case "greater_than":
-		value, err := castJSONPropertyToNumber(property)
-		if err != nil {
-			return err
+
+		for i, value := range values {
+			if err := r.compareValue(ctx, value, target); err != nil {
+				return r.errorf("value %d of %d at %q: %s", i+1, len(values), text, err.Error())
+			}
 		}
-		targetFloat, err := strconv.ParseFloat(target, 64)
-		if err != nil {
-			return err
+		return nil
+	default:
+		return r.errorf("not implemented match %q", match)
+	}
+}
+
+// assertJSONDocument compares value with JSON document target, every
+// difference is reported with its JSON path
+func (r *Resource) assertJSONDocument(value interface{}, target string) error {
+	expected, err := resource.DecodeJSON([]byte(target))
+	if err != nil {
+		return r.errorf("%s comparison failed, invalid target JSON document: %s", r.comparison, err.Error())
+	}
+
+	root := "$"
+	if r.property != "" {
+		root = r.property
+	}
+
+	var diffs []string
+	if r.comparison == "json_contains" {
+		diffs = jsonContainsDiff(expected, value, root, nil)
+	} else {
+		diffs = jsonDiff(expected, value, root, nil)
+	}
+
+	if len(diffs) > 0 {
+		return r.errorf("%s comparison failed, %d differences:%s", r.comparison, len(diffs), formatDiffs(diffs))
+	}
+	return nil
+}
+
+// jsonPath returns compiled property, interpolated properties are
+// compiled when the assertion runs
+func (r *Resource) jsonPath(ctx *resource.ExecutionContext) (*jsonpath.Path, error) {
+	if r.path != nil {
+		return r.path, nil
+	}
+
+	text, err := interpolator.Eval(r.property, ctx)
+	if err != nil {
+		return nil, err
+	}
+	return jsonpath.Compile(text)
+}
+
+// compareValue compares one of the values selected by JSON path,
+// selector or header name
+func (r *Resource) compareValue(ctx *resource.ExecutionContext, value interface{}, target string) error {
+	if r.source == "header" {
+		return r.compareHeader(ctx, value.(string), target)
+	}
+	return r.compareJSON(ctx, value, target)
+}
+
+// markupSelector returns compiled property of xml_body and html_body
+// sources, interpolated properties are compiled when the assertion runs
+func (r *Resource) markupSelector(ctx *resource.ExecutionContext) (*markup.Selector, error) {
+	if r.selector != nil {
+		return r.selector, nil
+	}
+
+	text, err := interpolator.Eval(r.property, ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	selector, err := markup.Compile(r.source, text)
+	if err != nil {
+		return nil, r.errorf("%s", err.Error())
+	}
+	return selector, nil
+}
+
+// compareJSON compares one JSON value selected by property with target,
+// values selected from XML and HTML bodies are compared the same way
+func (r *Resource) compareJSON(ctx *resource.ExecutionContext, property interface{}, target string) error {
+	switch r.comparison {
+	case "equals":
+		value, err := castJSONPropertyToString(property)
+		if err != nil {
+			return err
 		}
-		if value <= targetFloat {
-			return r.errorf("greater_than comparison failed, %f <= %f", value, targetFloat)
+		if value != target {
+			return r.errorf("equals comparison failed, %s != %s", value, target)
 		}
-	case "greater_than_or_equal":
-		value, err := castJSONPropertyToNumber(property)
+	case "does_not_equal":
+		value, err := castJSONPropertyToString(property)
 		if err != nil {
 			return err
 		}
-		targetFloat, err := strconv.ParseFloat(target, 64)
+		if value == target {
+			return r.errorf("does_not_equals comparison failed, %s == %s", value, target)
+		}
+	case "less_than", "less_than_or_equal", "greater_than", "greater_than_or_equal":
+		value, err := r.castNumber(property)
 		if err != nil {
 			return err
 		}
-		if value < targetFloat {
-			return r.errorf("greater_than_or_equal comparison failed, %f < %f", value, targetFloat)
-		}
+		return r.assertNumber(value, target)
 	case "contains":
 		stringProp, ok := property.(string)
 		if !ok {
@@ -423,7 +1004,7 @@ func (r *Resource) assertJSONBody(ctx *resource.ExecutionContext) error {
 			return r.errorf("equals_number comparison failed, %s", err.Error())
 		}
 
-		propNumber, err := castJSONPropertyToNumber(property)
+		propNumber, err := r.castNumber(property)
 		if err != nil {
 			return r.errorf("equals_number comparison failed, %s", err.Error())
 		}
@@ -436,9 +1017,27 @@ func (r *Resource) assertJSONBody(ctx *resource.ExecutionContext) error {
 			return r.errorf("is_null comparison failed")
 		}
 	case "is_a_number":
-		if _, err := castJSONPropertyToNumber(property); err != nil {
+		if _, err := r.castNumber(property); err != nil {
 			return r.errorf("is_a_number comparison failed, %s", err.Error())
 		}
+	case "matches", "does_not_match":
+		value, err := castJSONPropertyToString(property)
+		if err != nil {
+			return r.errorf("%s comparison failed, %s", r.comparison, err.Error())
+		}
+		return r.assertMatch(ctx, value, target)
+	case "json_equals", "json_contains":
+		return r.assertJSONDocument(property, target)
+	case "is_string", "is_bool", "is_array", "is_object":
+		return r.assertType(property)
+	case "length_equals", "length_greater_than", "length_less_than":
+		return r.assertLength(property, target)
+	case "one_of":
+		value, err := castJSONPropertyToString(property)
+		if err != nil {
+			return r.errorf("one_of comparison failed, %s", err.Error())
+		}
+		return r.assertOneOf(ctx, value)
 	default:
 		return r.errorf("not implemented comparison %q", r.comparison)
 	}
@@ -479,6 +1078,8 @@ func (r *Resource) assertText(value string, target string) error {
 		if strings.Contains(value, target) == true {
 			return r.errorf("does_not_contain comparison failed, %q in %q", target, value)
 		}
+	case "length_equals", "length_greater_than", "length_less_than":
+		return r.assertLength(value, target)
 	default:
 		return r.errorf("not implemented comparison %q", r.comparison)
 	}
@@ -492,19 +1093,406 @@ func (r *Resource) assertBody(ctx *resource.ExecutionContext) error {
 		return r.errorf("%s", err.Error())
 	}
 
+	if stringInSlice(r.comparison, MatchComparisons) {
+		return r.assertMatch(ctx, string(body), target)
+	}
+
+	if r.comparison == "matches_snapshot" {
+		return r.assertSnapshot(ctx)
+	}
+
+	if r.comparison == "one_of" {
+		return r.assertOneOf(ctx, string(body))
+	}
+
 	return r.assertText(string(body), target)
 }
 
+// assertHeader compares the first value of a header, or all values of
+// repeated headers if match is set. Header names are case-insensitive.
 func (r *Resource) assertHeader(ctx *resource.ExecutionContext) error {
-	header := ctx.CurrentResponse.Header.Get(r.property)
+	name, err := interpolator.Eval(r.property, ctx)
+	if err != nil {
+		return r.errorf("%s", err.Error())
+	}
+
 	target, err := interpolator.Eval(r.target, ctx)
 	if err != nil {
 		return r.errorf("%s", err.Error())
 	}
 
+	values := ctx.CurrentResponse.Header.Values(name)
+
+	switch r.comparison {
+	case "header_exists":
+		if len(values) == 0 {
+			return r.errorf("header_exists comparison failed, %s header is not set", name)
+		}
+		return nil
+	case "header_absent":
+		if len(values) != 0 {
+			return r.errorf("header_absent comparison failed, %s header is set to %q", name, strings.Join(values, ", "))
+		}
+		return nil
+	}
+
+	if r.match == "" {
+		header := ""
+		if len(values) > 0 {
+			header = values[0]
+		}
+		return r.compareHeader(ctx, header, target)
+	}
+
+	if len(values) == 0 && r.match != "count" {
+		return r.errorf("%s comparison failed, %s header is not set", r.comparison, name)
+	}
+
+	items := make([]interface{}, len(values))
+	for i, value := range values {
+		items[i] = value
+	}
+
+	match := r.match
+	switch match {
+	case "all_values":
+		match = "all"
+	case "any_value":
+		match = "any"
+	}
+	return r.assertValues(ctx, items, match, "header", name, target)
+}
+
+// compareHeader compares one header value, or its media type or
+// parameter if attribute is set
+func (r *Resource) compareHeader(ctx *resource.ExecutionContext, header string, target string) error {
+	header, err := resource.HeaderAttribute(header, r.attribute)
+	if err != nil {
+		return r.errorf("%s comparison failed, %s", r.comparison, err.Error())
+	}
+
+	if stringInSlice(r.comparison, MatchComparisons) {
+		return r.assertMatch(ctx, header, target)
+	}
+
+	if r.comparison == "one_of" {
+		return r.assertOneOf(ctx, header)
+	}
+
 	return r.assertText(header, target)
 }
 
+func (r *Resource) assertCookie(ctx *resource.ExecutionContext) error {
+	name, err := interpolator.Eval(r.property, ctx)
+	if err != nil {
+		return r.errorf("%s", err.Error())
+	}
+
+	target, err := interpolator.Eval(r.target, ctx)
+	if err != nil {
+		return r.errorf("%s", err.Error())
+	}
+
+	cookie := resource.FindCookie(ctx.CurrentResponse, name)
+	if cookie == nil {
+		return r.errorf("cookie %q is not set", name)
+	}
+
+	value, err := resource.CookieAttribute(cookie, r.attribute)
+	if err != nil {
+		return r.errorf("%s", err.Error())
+	}
+
+	switch r.comparison {
+	case "less_than", "less_than_or_equal", "greater_than", "greater_than_or_equal":
+		number, err := castJSONPropertyToNumber(value)
+		if err != nil {
+			return r.errorf("%s comparison failed, cookie attribute is not a number", r.comparison)
+		}
+		return r.assertNumber(number, target)
+	}
+
+	text, err := resource.ToString(value)
+	if err != nil {
+		return r.errorf("%s", err.Error())
+	}
+	return r.assertText(text, target)
+}
+
+// assertURL compares requested URL, or one of its query parameters
+// if property is set
+func (r *Resource) assertURL(ctx *resource.ExecutionContext) error {
+	if ctx.CurrentResult == nil {
+		return r.errorf("url is not recorded")
+	}
+
+	target, err := interpolator.Eval(r.target, ctx)
+	if err != nil {
+		return r.errorf("%s", err.Error())
+	}
+
+	if r.property == "" {
+		return r.assertText(ctx.CurrentResult.URL, target)
+	}
+
+	u, err := url.Parse(ctx.CurrentResult.URL)
+	if err != nil {
+		return r.errorf("%s", err.Error())
+	}
+
+	return r.assertText(u.Query().Get(r.property), target)
+}
+
+// assertBodySize compares size of the whole body, including the part
+// over max_body_size
+func (r *Resource) assertBodySize(ctx *resource.ExecutionContext) error {
+	if ctx.CurrentResult == nil {
+		return r.errorf("body size is not recorded")
+	}
+
+	target, err := interpolator.Eval(r.target, ctx)
+	if err != nil {
+		return r.errorf("%s", err.Error())
+	}
+
+	size := ctx.CurrentResult.BodySize
+	switch r.comparison {
+	case "equals", "does_not_equal":
+		return r.assertText(strconv.FormatInt(size, 10), target)
+	}
+	return r.assertNumber(float64(size), target)
+}
+
+func (r *Resource) assertBodyHash(ctx *resource.ExecutionContext) error {
+	if ctx.CurrentResult == nil {
+		return r.errorf("body hash is not recorded")
+	}
+
+	target, err := interpolator.Eval(r.target, ctx)
+	if err != nil {
+		return r.errorf("%s", err.Error())
+	}
+
+	algorithm := r.property
+	if algorithm == "" {
+		algorithm = "sha256"
+	}
+
+	return r.assertText(ctx.CurrentResult.BodyHashes[algorithm], strings.ToLower(target))
+}
+
+// assertContentEncoding compares encoding the body was sent with,
+// bodies are decoded before other assertions
+func (r *Resource) assertContentEncoding(ctx *resource.ExecutionContext) error {
+	if ctx.CurrentResult == nil {
+		return r.errorf("content encoding is not recorded")
+	}
+
+	target, err := interpolator.Eval(r.target, ctx)
+	if err != nil {
+		return r.errorf("%s", err.Error())
+	}
+
+	return r.assertText(ctx.CurrentResult.ContentEncoding, target)
+}
+
+// assertResponseTime compares duration of a request phase, total time
+// by default
+func (r *Resource) assertResponseTime(ctx *resource.ExecutionContext) error {
+	if ctx.CurrentResult == nil {
+		return r.errorf("response time is not recorded")
+	}
+
+	target, err := interpolator.Eval(r.target, ctx)
+	if err != nil {
+		return r.errorf("%s", err.Error())
+	}
+
+	limit, err := time.ParseDuration(target)
+	if err != nil {
+		return r.errorf("%s comparison failed, invalid duration %q", r.comparison, target)
+	}
+
+	phase := r.property
+	if phase == "" {
+		phase = "total"
+	}
+
+	value, err := ctx.CurrentResult.Timings.Phase(phase)
+	if err != nil {
+		return r.errorf("%s", err.Error())
+	}
+
+	switch r.comparison {
+	case "less_than":
+		if value >= limit {
+			return r.errorf("less_than comparison failed, %s time %s >= %s", phase, value.Round(time.Microsecond), limit)
+		}
+	case "less_than_or_equal":
+		if value > limit {
+			return r.errorf("less_than_or_equal comparison failed, %s time %s > %s", phase, value.Round(time.Microsecond), limit)
+		}
+	case "greater_than":
+		if value <= limit {
+			return r.errorf("greater_than comparison failed, %s time %s <= %s", phase, value.Round(time.Microsecond), limit)
+		}
+	case "greater_than_or_equal":
+		if value < limit {
+			return r.errorf("greater_than_or_equal comparison failed, %s time %s < %s", phase, value.Round(time.Microsecond), limit)
+		}
+	default:
+		return r.errorf("not implemented comparison %q", r.comparison)
+	}
+	return nil
+}
+
+// assertMatch matches value with the regular expression target, named
+// groups are stored as variables if capture is set
+func (r *Resource) assertMatch(ctx *resource.ExecutionContext, value string, target string) error {
+	pattern := r.pattern
+	if pattern == nil {
+		compiled, err := regexp.Compile(target)
+		if err != nil {
+			return r.errorf("%s comparison failed, invalid regular expression: %s", r.comparison, err.Error())
+		}
+		pattern = compiled
+	}
+
+	match := pattern.FindStringSubmatch(value)
+
+	switch r.comparison {
+	case "matches":
+		if match == nil {
+			return r.errorf("matches comparison failed, %q does not match %q", value, pattern)
+		}
+
+		if r.capture {
+			for i, name := range pattern.SubexpNames() {
+				if name != "" {
+					ctx.SetVariable(name, match[i])
+				}
+			}
+		}
+	case "does_not_match":
+		if match != nil {
+			return r.errorf("does_not_match comparison failed, %q matches %q", value, pattern)
+		}
+	default:
+		return r.errorf("not implemented comparison %q", r.comparison)
+	}
+	return nil
+}
+
+// assertType checks JSON type of value
+func (r *Resource) assertType(value interface{}) error {
+	expected := map[string]string{
+		"is_string": "string",
+		"is_bool":   "boolean",
+		"is_array":  "list",
+		"is_object": "object",
+	}[r.comparison]
+
+	if actual := resource.TypeName(value); actual != expected {
+		return r.errorf("%s comparison failed, JSON property type is %s", r.comparison, actual)
+	}
+	return nil
+}
+
+// assertLength compares number of characters of a string, items of an
+// array or keys of an object with target
+func (r *Resource) assertLength(value interface{}, target string) error {
+	expected, err := strconv.Atoi(target)
+	if err != nil {
+		return r.errorf("%s comparison failed, invalid length %q", r.comparison, target)
+	}
+
+	var length int
+	switch value := value.(type) {
+	case string:
+		length = utf8.RuneCountInString(value)
+	case []interface{}:
+		length = len(value)
+	case map[string]interface{}:
+		length = len(value)
+	default:
+		return r.errorf("%s comparison failed, %s has no length", r.comparison, resource.TypeName(value))
+	}
+
+	switch r.comparison {
+	case "length_equals":
+		if length != expected {
+			return r.errorf("length_equals comparison failed, length %d != %d", length, expected)
+		}
+	case "length_greater_than":
+		if length <= expected {
+			return r.errorf("length_greater_than comparison failed, length %d <= %d", length, expected)
+		}
+	case "length_less_than":
+		if length >= expected {
+			return r.errorf("length_less_than comparison failed, length %d >= %d", length, expected)
+		}
+	default:
+		return r.errorf("not implemented comparison %q", r.comparison)
+	}
+	return nil
+}
+
+// assertOneOf checks that value equals one of target list items, items
+// are interpolated when the assertion runs
+func (r *Resource) assertOneOf(ctx *resource.ExecutionContext, value string) error {
+	targets := make([]string, 0, len(r.targets))
+	for _, item := range r.targets {
+		target, err := interpolator.Eval(item, ctx)
+		if err != nil {
+			return r.errorf("%s", err.Error())
+		}
+
+		if value == target {
+			return nil
+		}
+		targets = append(targets, target)
+	}
+	return r.errorf("one_of comparison failed, %q is not one of %s", value, formatJSON(targets))
+}
+
+func (r *Resource) assertNumber(value float64, target string) error {
+	targetFloat, err := strconv.ParseFloat(target, 64)
+	if err != nil {
+		return r.errorf("%s comparison failed, %s", r.comparison, err.Error())
+	}
+
+	switch r.comparison {
+	case "less_than":
+		if value >= targetFloat {
+			return r.errorf("less_than comparison failed, %f >= %f", value, targetFloat)
+		}
+	case "less_than_or_equal":
+		if value > targetFloat {
+			return r.errorf("less_than_or_equal comparison failed, %f > %f", value, targetFloat)
+		}
+	case "greater_than":
+		if value <= targetFloat {
+			return r.errorf("greater_than comparison failed, %f <= %f", value, targetFloat)
+		}
+	case "greater_than_or_equal":
+		if value < targetFloat {
+			return r.errorf("greater_than_or_equal comparison failed, %f < %f", value, targetFloat)
+		}
+	default:
+		return r.errorf("not implemented comparison %q", r.comparison)
+	}
+	return nil
+}
+
+func hasNamedGroups(pattern *regexp.Regexp) bool {
+	for _, name := range pattern.SubexpNames() {
+		if name != "" {
+			return true
+		}
+	}
+	return false
+}
+
 func stringInSlice(s string, list []string) bool {
 	for _, b := range list {
 		if s == b {
@@ -515,28 +1503,29 @@ func stringInSlice(s string, list []string) bool {
 }
 
 func castJSONPropertyToString(property interface{}) (string, error) {
-	var value string
-	switch property := property.(type) {
-	case bool:
-		if property {
-			value = "true"
-		} else {
-			value = "false"
-		}
-	case string:
-		value = property
-	case float64:
-		// both ints and floats end up here.
-		value = fmt.Sprintf("%f", property)
-	default:
+	switch property.(type) {
+	case []interface{}, map[string]interface{}, nil:
 		return "", fmt.Errorf("complex JSON fields are not supported")
 	}
-	return value, nil
+	return resource.ToString(property)
+}
+
+// castNumber casts JSON number, text selected from XML and HTML bodies
+// is parsed as a number
+func (r *Resource) castNumber(property interface{}) (float64, error) {
+	if text, ok := property.(string); ok && stringInSlice(r.source, MarkupSources) {
+		number, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number", text)
+		}
+		return number, nil
+	}
+	return castJSONPropertyToNumber(property)
 }
 
 func castJSONPropertyToNumber(property interface{}) (float64, error) {
-	if n, ok := property.(float64); ok {
-		return n, nil
+	if !resource.IsNumber(property) {
+		return 0, fmt.Errorf("JSON property is not a number")
 	}
-	return 0, fmt.Errorf("JSON property is not a number")
+	return resource.ToFloat64(property)
 }
@@ -2,15 +2,18 @@ package http_assertion
 
 import (
 	"github.com/bluebookrun/bluebook/bcl"
+	"github.com/bluebookrun/bluebook/bcl/bcltest"
 	"github.com/bluebookrun/bluebook/resource"
 	"github.com/stretchr/testify/assert"
 	"net/http"
 	"testing"
+	"time"
 )
 
 type inputCase struct {
 	source     string
 	property   string
+	attribute  string
 	comparison string
 	target     string
 	valid      bool
@@ -19,6 +22,7 @@ type inputCase struct {
 type assertionCase struct {
 	source     string
 	property   string
+	attribute  string
 	comparison string
 	target     string
 	valid      bool
@@ -26,6 +30,48 @@ type assertionCase struct {
 }
 
 func TestAssertions(t *testing.T) {
+	urlCtx := &resource.ExecutionContext{
+		CurrentResult: &resource.StepResult{
+			Method: "GET",
+			URL:    "http://example.com/search?q=a%26b&q=c+d",
+		},
+	}
+
+	bodyCtx := &resource.ExecutionContext{
+		CurrentResult: &resource.StepResult{
+			ContentEncoding: "br",
+			BodySize:        2048,
+			BodyTruncated:   true,
+			BodyHashes: map[string]string{
+				"md5":    "5d41402abc4b2a76b9719d911017c592",
+				"sha256": "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+			},
+			Timings: resource.Timings{
+				Connect: 20 * time.Millisecond,
+				TTFB:    180 * time.Millisecond,
+				Total:   250 * time.Millisecond,
+			},
+		},
+	}
+
+	matchCtx := &resource.ExecutionContext{
+		Variables: map[string]interface{}{
+			"prefix": "",
+		},
+		CurrentResponse: &http.Response{
+			Header: http.Header{
+				"Cache-Control": []string{"max-age=3600"},
+			},
+		},
+		CurrentResponseBody: []byte(`{"id": "0b6e3ba4-84a3-4c2f-9f3a-2f1e0c1b6f7d"}`),
+	}
+
+	cookieResponse := &http.Response{
+		Header: http.Header{
+			"Set-Cookie": []string{"session=secret; Max-Age=3600; HttpOnly; SameSite=Lax"},
+		},
+	}
+
 	assertionTestCases := []assertionCase{
 		{
 			source:     "body",
@@ -183,7 +229,7 @@ func TestAssertions(t *testing.T) {
 			valid:      true,
 			ctx: &resource.ExecutionContext{
 				CurrentResponseBody: []byte("body"),
-				Variables: map[string]string{
+				Variables: map[string]interface{}{
 					"v": "dy",
 				},
 			},
@@ -195,7 +241,7 @@ func TestAssertions(t *testing.T) {
 			valid:      false,
 			ctx: &resource.ExecutionContext{
 				CurrentResponseBody: []byte("body"),
-				Variables: map[string]string{
+				Variables: map[string]interface{}{
 					"v": "dy",
 				},
 			},
@@ -207,7 +253,7 @@ func TestAssertions(t *testing.T) {
 			valid:      true,
 			ctx: &resource.ExecutionContext{
 				CurrentResponseBody: []byte("body"),
-				Variables: map[string]string{
+				Variables: map[string]interface{}{
 					"v": "dy",
 				},
 			},
@@ -219,7 +265,7 @@ func TestAssertions(t *testing.T) {
 			valid:      false,
 			ctx: &resource.ExecutionContext{
 				CurrentResponseBody: []byte("body"),
-				Variables: map[string]string{
+				Variables: map[string]interface{}{
 					"v": "dy",
 				},
 			},
@@ -233,7 +279,7 @@ func TestAssertions(t *testing.T) {
 				CurrentResponse: &http.Response{
 					StatusCode: 200,
 				},
-				Variables: map[string]string{
+				Variables: map[string]interface{}{
 					"v": "00",
 				},
 			},
@@ -443,7 +489,7 @@ func TestAssertions(t *testing.T) {
 			target:     "${var.v}",
 			valid:      true,
 			ctx: &resource.ExecutionContext{
-				Variables: map[string]string{
+				Variables: map[string]interface{}{
 					"v": "content",
 				},
 				CurrentResponse: &http.Response{
@@ -461,7 +507,7 @@ func TestAssertions(t *testing.T) {
 			target:     "${var.v}",
 			valid:      true,
 			ctx: &resource.ExecutionContext{
-				Variables: map[string]string{
+				Variables: map[string]interface{}{
 					"v": "value",
 				},
 				CurrentResponse:     &http.Response{},
@@ -475,7 +521,7 @@ func TestAssertions(t *testing.T) {
 			target:     "${var.v}",
 			valid:      false,
 			ctx: &resource.ExecutionContext{
-				Variables: map[string]string{
+				Variables: map[string]interface{}{
 					"v": "value2",
 				},
 				CurrentResponse:     &http.Response{},
@@ -489,7 +535,7 @@ func TestAssertions(t *testing.T) {
 			target:     "${var.v}",
 			valid:      true,
 			ctx: &resource.ExecutionContext{
-				Variables: map[string]string{
+				Variables: map[string]interface{}{
 					"v": "value2",
 				},
 				CurrentResponse:     &http.Response{},
@@ -503,7 +549,7 @@ func TestAssertions(t *testing.T) {
 			target:     "${var.v}",
 			valid:      false,
 			ctx: &resource.ExecutionContext{
-				Variables: map[string]string{
+				Variables: map[string]interface{}{
 					"v": "value",
 				},
 				CurrentResponse:     &http.Response{},
@@ -974,12 +1020,308 @@ func TestAssertions(t *testing.T) {
 				CurrentResponseBody: []byte(`{"data": 123.3}`),
 			},
 		},
+		{
+			source:     "cookie",
+			property:   "session",
+			comparison: "equals",
+			target:     "secret",
+			valid:      true,
+			ctx: &resource.ExecutionContext{
+				CurrentResponse: cookieResponse,
+			},
+		},
+		{
+			source:     "cookie",
+			property:   "session",
+			comparison: "equals",
+			target:     "other",
+			valid:      false,
+			ctx: &resource.ExecutionContext{
+				CurrentResponse: cookieResponse,
+			},
+		},
+		{
+			source:     "cookie",
+			property:   "missing",
+			comparison: "equals",
+			target:     "secret",
+			valid:      false,
+			ctx: &resource.ExecutionContext{
+				CurrentResponse: cookieResponse,
+			},
+		},
+		{
+			source:     "cookie",
+			property:   "session",
+			attribute:  "http_only",
+			comparison: "equals",
+			target:     "true",
+			valid:      true,
+			ctx: &resource.ExecutionContext{
+				CurrentResponse: cookieResponse,
+			},
+		},
+		{
+			source:     "cookie",
+			property:   "session",
+			attribute:  "secure",
+			comparison: "equals",
+			target:     "false",
+			valid:      true,
+			ctx: &resource.ExecutionContext{
+				CurrentResponse: cookieResponse,
+			},
+		},
+		{
+			source:     "cookie",
+			property:   "session",
+			attribute:  "same_site",
+			comparison: "equals",
+			target:     "Lax",
+			valid:      true,
+			ctx: &resource.ExecutionContext{
+				CurrentResponse: cookieResponse,
+			},
+		},
+		{
+			source:     "cookie",
+			property:   "session",
+			attribute:  "expires_in",
+			comparison: "greater_than",
+			target:     "3000",
+			valid:      true,
+			ctx: &resource.ExecutionContext{
+				CurrentResponse: cookieResponse,
+			},
+		},
+		{
+			source:     "cookie",
+			property:   "session",
+			attribute:  "expires_in",
+			comparison: "less_than",
+			target:     "3000",
+			valid:      false,
+			ctx: &resource.ExecutionContext{
+				CurrentResponse: cookieResponse,
+			},
+		},
+		{
+			source:     "cookie",
+			property:   "session",
+			attribute:  "value",
+			comparison: "greater_than",
+			target:     "1",
+			valid:      false,
+			ctx: &resource.ExecutionContext{
+				CurrentResponse: cookieResponse,
+			},
+		},
+		{
+			source:     "url",
+			comparison: "equals",
+			target:     "http://example.com/search?q=a%26b&q=c+d",
+			valid:      true,
+			ctx:        urlCtx,
+		},
+		{
+			source:     "url",
+			property:   "q",
+			comparison: "equals",
+			target:     "a&b",
+			valid:      true,
+			ctx:        urlCtx,
+		},
+		{
+			source:     "url",
+			property:   "page",
+			comparison: "is_empty",
+			valid:      true,
+			ctx:        urlCtx,
+		},
+		{
+			source:     "url",
+			comparison: "contains",
+			target:     "q=x",
+			valid:      false,
+			ctx:        urlCtx,
+		},
+		{
+			source:     "url",
+			comparison: "is_not_empty",
+			valid:      false,
+			ctx:        &resource.ExecutionContext{},
+		},
+		{
+			source:     "body_size",
+			comparison: "equals",
+			target:     "2048",
+			valid:      true,
+			ctx:        bodyCtx,
+		},
+		{
+			source:     "body_size",
+			comparison: "greater_than",
+			target:     "1024",
+			valid:      true,
+			ctx:        bodyCtx,
+		},
+		{
+			source:     "body_size",
+			comparison: "less_than",
+			target:     "1024",
+			valid:      false,
+			ctx:        bodyCtx,
+		},
+		{
+			source:     "body_hash",
+			comparison: "equals",
+			target:     "2CF24DBA5FB0A30E26E83B2AC5B9E29E1B161E5C1FA7425E73043362938B9824",
+			valid:      true,
+			ctx:        bodyCtx,
+		},
+		{
+			source:     "body_hash",
+			property:   "md5",
+			comparison: "equals",
+			target:     "5d41402abc4b2a76b9719d911017c592",
+			valid:      true,
+			ctx:        bodyCtx,
+		},
+		{
+			source:     "body_hash",
+			property:   "md5",
+			comparison: "does_not_equal",
+			target:     "5d41402abc4b2a76b9719d911017c592",
+			valid:      false,
+			ctx:        bodyCtx,
+		},
+		{
+			source:     "content_encoding",
+			comparison: "equals",
+			target:     "br",
+			valid:      true,
+			ctx:        bodyCtx,
+		},
+		{
+			source:     "content_encoding",
+			comparison: "is_empty",
+			target:     "",
+			valid:      false,
+			ctx:        bodyCtx,
+		},
+		{
+			source:     "response_time",
+			comparison: "less_than",
+			target:     "300ms",
+			valid:      true,
+			ctx:        bodyCtx,
+		},
+		{
+			source:     "response_time",
+			comparison: "less_than",
+			target:     "250ms",
+			valid:      false,
+			ctx:        bodyCtx,
+		},
+		{
+			source:     "response_time",
+			comparison: "less_than_or_equal",
+			target:     "250ms",
+			valid:      true,
+			ctx:        bodyCtx,
+		},
+		{
+			source:     "response_time",
+			property:   "ttfb",
+			comparison: "greater_than",
+			target:     "0.2s",
+			valid:      false,
+			ctx:        bodyCtx,
+		},
+		{
+			source:     "response_time",
+			property:   "connect",
+			comparison: "greater_than_or_equal",
+			target:     "20ms",
+			valid:      true,
+			ctx:        bodyCtx,
+		},
+		{
+			source:     "response_time",
+			property:   "dns",
+			comparison: "less_than",
+			target:     "1ms",
+			valid:      true,
+			ctx:        bodyCtx,
+		},
+		{
+			source:     "response_time",
+			comparison: "less_than",
+			target:     "1s",
+			valid:      false,
+			ctx:        &resource.ExecutionContext{},
+		},
+		{
+			source:     "body",
+			comparison: "matches",
+			target:     `^\{"id": "[0-9a-f-]{36}"\}$`,
+			valid:      true,
+			ctx:        matchCtx,
+		},
+		{
+			source:     "body",
+			comparison: "does_not_match",
+			target:     `"id"`,
+			valid:      false,
+			ctx:        matchCtx,
+		},
+		{
+			source:     "header",
+			property:   "Cache-Control",
+			comparison: "matches",
+			target:     `^max-age=\d+$`,
+			valid:      true,
+			ctx:        matchCtx,
+		},
+		{
+			source:     "header",
+			property:   "Cache-Control",
+			comparison: "matches",
+			target:     `^no-cache`,
+			valid:      false,
+			ctx:        matchCtx,
+		},
+		{
+			source:     "header",
+			property:   "Cache-Control",
+			comparison: "does_not_match",
+			target:     `no-store`,
+			valid:      true,
+			ctx:        matchCtx,
+		},
+		{
+			source:     "json_body",
+			property:   "id",
+			comparison: "matches",
+			target:     `^${var.prefix}[0-9a-f]{8}-`,
+			valid:      true,
+			ctx:        matchCtx,
+		},
+		{
+			source:     "json_body",
+			property:   "id",
+			comparison: "matches",
+			target:     `[`,
+			valid:      false,
+			ctx:        matchCtx,
+		},
 	}
 
 	for _, c := range assertionTestCases {
 		resource := &Resource{
 			source:     c.source,
 			property:   c.property,
+			attribute:  c.attribute,
 			comparison: c.comparison,
 			target:     c.target,
 			Node: &bcl.BlockNode{
@@ -1300,12 +1642,163 @@ func TestInputValidation(t *testing.T) {
 			target:     "a",
 			valid:      true,
 		},
+		{
+			source:     "cookie",
+			property:   "",
+			comparison: "equals",
+			target:     "a",
+			valid:      false,
+		},
+		{
+			source:     "cookie",
+			property:   "session",
+			comparison: "equals",
+			target:     "a",
+			valid:      true,
+		},
+		{
+			source:     "cookie",
+			property:   "session",
+			attribute:  "http_only",
+			comparison: "equals",
+			target:     "true",
+			valid:      true,
+		},
+		{
+			source:     "cookie",
+			property:   "session",
+			attribute:  "color",
+			comparison: "equals",
+			target:     "a",
+			valid:      false,
+		},
+		{
+			source:     "cookie",
+			property:   "session",
+			comparison: "has_key",
+			target:     "a",
+			valid:      false,
+		},
+		{
+			source:     "url",
+			property:   "a",
+			attribute:  "value",
+			comparison: "is_empty",
+			valid:      false,
+		},
+		{
+			source:     "url",
+			comparison: "equals",
+			target:     "http://example.com",
+			valid:      true,
+		},
+		{
+			source:     "url",
+			comparison: "has_key",
+			target:     "a",
+			valid:      false,
+		},
+		{
+			source:     "body_size",
+			comparison: "is_empty",
+			target:     "",
+			valid:      false,
+		},
+		{
+			source:     "body_size",
+			comparison: "less_than",
+			target:     "10",
+			valid:      true,
+		},
+		{
+			source:     "body_hash",
+			property:   "crc32",
+			comparison: "equals",
+			target:     "a",
+			valid:      false,
+		},
+		{
+			source:     "body_hash",
+			property:   "sha1",
+			comparison: "equals",
+			target:     "a",
+			valid:      true,
+		},
+		{
+			source:     "content_encoding",
+			comparison: "equals",
+			target:     "gzip",
+			valid:      true,
+		},
+		{
+			source:     "response_time",
+			property:   "tls",
+			comparison: "less_than",
+			target:     "100ms",
+			valid:      true,
+		},
+		{
+			source:     "response_time",
+			comparison: "less_than",
+			target:     "${var.latency}",
+			valid:      true,
+		},
+		{
+			source:     "response_time",
+			comparison: "less_than",
+			target:     "300",
+			valid:      false,
+		},
+		{
+			source:     "response_time",
+			property:   "body",
+			comparison: "less_than",
+			target:     "300ms",
+			valid:      false,
+		},
+		{
+			source:     "response_time",
+			comparison: "equals",
+			target:     "300ms",
+			valid:      false,
+		},
+		{
+			source:     "body",
+			comparison: "matches",
+			target:     `^[a-z]+$`,
+			valid:      true,
+		},
+		{
+			source:     "body",
+			comparison: "matches",
+			target:     `[a-z`,
+			valid:      false,
+		},
+		{
+			source:     "body",
+			comparison: "matches",
+			target:     `${var.pattern}`,
+			valid:      true,
+		},
+		{
+			source:     "body",
+			comparison: "does_not_match",
+			target:     "",
+			valid:      false,
+		},
+		{
+			source:     "status_code",
+			comparison: "matches",
+			target:     `^2`,
+			valid:      false,
+		},
 	}
 
 	for _, c := range inputTestCases {
 		resource := &Resource{
 			source:     c.source,
 			property:   c.property,
+			attribute:  c.attribute,
 			comparison: c.comparison,
 			target:     c.target,
 			Node: &bcl.BlockNode{
@@ -1327,3 +1820,385 @@ func TestInputValidation(t *testing.T) {
 		}
 	}
 }
+
+func TestUnknownBlock(t *testing.T) {
+	_, err := New(bcltest.Block(t, `resource "http_assertion" "a" {
+		source = "status_code"
+		comparison = "equals"
+		target = "200"
+		auth "basic" { username = "a" }
+	}`))
+	assert.EqualError(t, err, `unknown block "auth"`)
+}
+
+func TestMatchCapture(t *testing.T) {
+	r, err := New(bcltest.Block(t, `resource "http_assertion" "location" {
+		source = "header"
+		property = "Location"
+		comparison = "matches"
+		target = "/orders/(?P<order_id>\d+)/items/(?P<item_id>\d+)"
+		capture = true
+	}`))
+	assert.Nil(t, err)
+
+	ctx := resource.NewExecutionContext()
+	ctx.CurrentResponse = &http.Response{
+		Header: http.Header{"Location": []string{"/orders/42/items/7"}},
+	}
+	assert.Nil(t, r.Exec(ctx))
+	assert.Equal(t, map[string]interface{}{"order_id": "42", "item_id": "7"}, ctx.Variables)
+
+	invalid := []string{
+		`resource "http_assertion" "a" { source = "body", comparison = "matches", target = "\d+", capture = true }`,
+		`resource "http_assertion" "a" { source = "body", comparison = "does_not_match", target = "(?P<id>\d+)", capture = true }`,
+		`resource "http_assertion" "a" { source = "body", comparison = "matches", target = "(?P<id>\d+)", capture = "maybe" }`,
+	}
+
+	for _, text := range invalid {
+		_, err := New(bcltest.Block(t, text))
+		assert.NotNil(t, err, text)
+	}
+}
+
+func TestJSONPathMatch(t *testing.T) {
+	body := `[
+		{"id": 1, "status": "active", "price": 5},
+		{"id": 2, "status": "active", "price": 15},
+		{"id": 3, "status": "deleted", "price": 25}
+	]`
+
+	cases := []struct {
+		text  string
+		valid bool
+	}{
+		// root arrays and definite paths
+		{`property = "$[1].id", comparison = "equals", target = "2"`, true},
+		{`property = "$[-1].status", comparison = "equals", target = "deleted"`, true},
+		{`property = "$[5].id", comparison = "equals", target = "2"`, false},
+		// all is the default for paths selecting many values
+		{`property = "$[*].price", comparison = "greater_than", target = "1"`, true},
+		{`property = "$[*].price", comparison = "greater_than", target = "10"`, false},
+		{`property = "$[?(@.status == 'active')].price", match = "all", comparison = "less_than", target = "20"`, true},
+		{`property = "$[?(@.status == 'missing')].price", match = "all", comparison = "less_than", target = "20"`, false},
+		{`property = "$[*].status", match = "any", comparison = "equals", target = "deleted"`, true},
+		{`property = "$[0:2].status", match = "any", comparison = "equals", target = "deleted"`, false},
+		{`property = "$[?(@.price > 10)]", match = "count", comparison = "equals", target = "2"`, true},
+		{`property = "$[?(@.price > 10)]", match = "count", comparison = "greater_than", target = "2"`, false},
+		{`property = "$..id", match = "count", comparison = "less_than_or_equal", target = "3"`, true},
+		// count of a definite path is 0 or 1
+		{`property = "$[7]", match = "count", comparison = "equals", target = "0"`, true},
+	}
+
+	for _, c := range cases {
+		r, err := New(bcltest.Block(t, `resource "http_assertion" "a" { source = "json_body", `+c.text+` }`))
+		if !assert.Nil(t, err, c.text) {
+			continue
+		}
+
+		err = r.Exec(bodyContext(body))
+		if c.valid {
+			assert.Nil(t, err, c.text)
+		} else {
+			assert.NotNil(t, err, c.text)
+		}
+	}
+
+	r, err := New(bcltest.Block(t, `resource "http_assertion" "a" {
+		source = "json_body"
+		property = "$[*].price"
+		comparison = "less_than"
+		target = "20"
+	}`))
+	assert.Nil(t, err)
+	assert.EqualError(t, r.Exec(bodyContext(body)),
+		`value 3 of 3 at "$[*].price": less_than comparison failed, 25.000000 >= 20.000000`)
+
+	invalid := []string{
+		`resource "http_assertion" "a" { source = "json_body", property = "$[?(@.a ==)]", comparison = "is_null" }`,
+		`resource "http_assertion" "a" { source = "json_body", property = "$[*]", match = "some", comparison = "is_null" }`,
+		`resource "http_assertion" "a" { source = "json_body", property = "$[*]", match = "count", comparison = "is_null" }`,
+		`resource "http_assertion" "a" { source = "body", match = "all", comparison = "is_empty" }`,
+		`resource "http_assertion" "a" { source = "json_body", match = "all", comparison = "matches_schema", target = "{}" }`,
+	}
+
+	for _, text := range invalid {
+		_, err := New(bcltest.Block(t, text))
+		assert.NotNil(t, err, text)
+	}
+}
+
+func TestMarkupBody(t *testing.T) {
+	xmlBody := `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/">
+  <soap:Body>
+    <GetOrdersResponse>
+      <order id="1" status="paid"><total>10.50</total></order>
+      <order id="2" status="pending"><total>4</total></order>
+    </GetOrdersResponse>
+  </soap:Body>
+</soap:Envelope>`
+
+	htmlBody := `<html>
+<head><title>Orders</title></head>
+<body>
+  <ul class="orders">
+    <li class="paid"><a href="/orders/1">Order 1</a></li>
+    <li class="pending"><a href="/orders/2">Order 2</a></li>
+  </ul>
+  <p class="empty"></p>
+</body>
+</html>`
+
+	cases := []struct {
+		text  string
+		body  string
+		valid bool
+	}{
+		{`source = "xml_body", property = "//order[@id='1']/total", comparison = "equals", target = "10.50"`, xmlBody, true},
+		{`source = "xml_body", property = "//order[@id='1']/total", comparison = "equals_number", target = "10.5"`, xmlBody, true},
+		{`source = "xml_body", property = "//soap:Body//order/total", comparison = "less_than", target = "20"`, xmlBody, true},
+		{`source = "xml_body", property = "//order/total", comparison = "greater_than", target = "5"`, xmlBody, false},
+		{`source = "xml_body", property = "//order/total", match = "any", comparison = "greater_than", target = "5"`, xmlBody, true},
+		{`source = "xml_body", property = "//order", match = "count", comparison = "equals", target = "2"`, xmlBody, true},
+		{`source = "xml_body", property = "count(//order)", comparison = "equals", target = "2"`, xmlBody, true},
+		{`source = "xml_body", property = "//order", attribute = "status", match = "any", comparison = "equals", target = "pending"`, xmlBody, true},
+		{`source = "xml_body", property = "//order/@status", comparison = "matches", target = "^(paid|pending)$"`, xmlBody, true},
+		{`source = "xml_body", property = "//order[@id='1']", attribute = "status", comparison = "is_a_number"`, xmlBody, false},
+		{`source = "xml_body", property = "//invoice", comparison = "is_not_empty"`, xmlBody, false},
+		{`source = "xml_body", property = "//order", comparison = "is_not_empty"`, `<orders><order>`, false},
+		{`source = "html_body", property = "title", comparison = "equals", target = "Orders"`, htmlBody, true},
+		{`source = "html_body", property = "ul.orders > li", comparison = "contains", target = "Order"`, htmlBody, true},
+		{`source = "html_body", property = "li.paid a", attribute = "href", comparison = "equals", target = "/orders/1"`, htmlBody, true},
+		{`source = "html_body", property = "li a", attribute = "href", comparison = "does_not_contain", target = "2"`, htmlBody, false},
+		{`source = "html_body", property = "p.empty", comparison = "is_empty"`, htmlBody, true},
+		{`source = "html_body", property = "//li[@class='pending']/a", comparison = "equals", target = "Order 2"`, htmlBody, true},
+		{`source = "html_body", property = "li", match = "count", comparison = "greater_than", target = "2"`, htmlBody, false},
+	}
+
+	for _, c := range cases {
+		r, err := New(bcltest.Block(t, `resource "http_assertion" "a" { `+c.text+` }`))
+		if !assert.Nil(t, err, c.text) {
+			continue
+		}
+
+		err = r.Exec(bodyContext(c.body))
+		if c.valid {
+			assert.Nil(t, err, c.text)
+		} else {
+			assert.NotNil(t, err, c.text)
+		}
+	}
+
+	r, err := New(bcltest.Block(t, `resource "http_assertion" "a" {
+		source = "xml_body"
+		property = "//order/total"
+		comparison = "less_than"
+		target = "5"
+	}`))
+	assert.Nil(t, err)
+	assert.EqualError(t, r.Exec(bodyContext(xmlBody)),
+		`value 1 of 2 at "//order/total": less_than comparison failed, 10.500000 >= 5.000000`)
+
+	// selectors are compiled when the assertion runs if interpolated
+	r, err = New(bcltest.Block(t, `resource "http_assertion" "a" {
+		source = "html_body"
+		property = "li.${var.status} a"
+		comparison = "equals"
+		target = "Order 2"
+	}`))
+	assert.Nil(t, err)
+	ctx := bodyContext(htmlBody)
+	ctx.SetVariable("status", "pending")
+	assert.Nil(t, r.Exec(ctx))
+
+	invalid := []string{
+		`resource "http_assertion" "a" { source = "xml_body", comparison = "is_empty" }`,
+		`resource "http_assertion" "a" { source = "xml_body", property = "//order[", comparison = "is_empty" }`,
+		`resource "http_assertion" "a" { source = "html_body", property = "ul >", comparison = "is_empty" }`,
+		`resource "http_assertion" "a" { source = "html_body", property = "ul", comparison = "has_key", target = "a" }`,
+		`resource "http_assertion" "a" { source = "html_body", property = "ul", comparison = "json_equals", target = "{}" }`,
+		`resource "http_assertion" "a" { source = "body", attribute = "rel", comparison = "is_empty" }`,
+	}
+
+	for _, text := range invalid {
+		_, err := New(bcltest.Block(t, text))
+		assert.NotNil(t, err, text)
+	}
+}
+
+func TestTypeAndLengthComparisons(t *testing.T) {
+	body := `{"name": "Zoë", "active": true, "tags": ["a", "b"], "address": {"city": "Oslo"}, "count": 2, "missing": null}`
+
+	cases := []struct {
+		text  string
+		valid bool
+	}{
+		{`property = "name", comparison = "is_string"`, true},
+		{`property = "count", comparison = "is_string"`, false},
+		{`property = "active", comparison = "is_bool"`, true},
+		{`property = "name", comparison = "is_bool"`, false},
+		{`property = "tags", comparison = "is_array"`, true},
+		{`property = "address", comparison = "is_array"`, false},
+		{`property = "address", comparison = "is_object"`, true},
+		{`property = "missing", comparison = "is_object"`, false},
+		{`property = "name", comparison = "length_equals", target = "3"`, true},
+		{`property = "tags", comparison = "length_equals", target = "3"`, false},
+		{`property = "tags", comparison = "length_greater_than", target = "1"`, true},
+		{`property = "address", comparison = "length_less_than", target = "2"`, true},
+		{`property = "address", comparison = "length_less_than", target = "1"`, false},
+		{`property = "count", comparison = "length_equals", target = "2"`, false},
+		{`property = "name", comparison = "one_of", target = ["Ann", "Zoë"]`, true},
+		{`property = "count", comparison = "one_of", target = ["1", "2"]`, true},
+		{`property = "tags[0]", comparison = "one_of", target = ["b", "c"]`, false},
+		{`property = "tags", comparison = "one_of", target = ["a"]`, false},
+		{`property = "$.tags[*]", comparison = "one_of", target = ["a", "b"]`, true},
+	}
+
+	for _, c := range cases {
+		r, err := New(bcltest.Block(t, `resource "http_assertion" "a" { source = "json_body", `+c.text+` }`))
+		if !assert.Nil(t, err, c.text) {
+			continue
+		}
+
+		err = r.Exec(bodyContext(body))
+		if c.valid {
+			assert.Nil(t, err, c.text)
+		} else {
+			assert.NotNil(t, err, c.text)
+		}
+	}
+
+	ctx := bodyContext("pending")
+	ctx.CurrentResponse.Header = http.Header{"X-Status": []string{"paid"}}
+	ctx.SetVariable("status", "paid")
+
+	textCases := []struct {
+		text  string
+		valid bool
+	}{
+		{`source = "body", comparison = "length_equals", target = "7"`, true},
+		{`source = "body", comparison = "length_greater_than", target = "7"`, false},
+		{`source = "body", comparison = "one_of", target = ["paid", "pending"]`, true},
+		{`source = "body", comparison = "one_of", target = ["paid", "refunded"]`, false},
+		{`source = "header", property = "X-Status", comparison = "length_less_than", target = "5"`, true},
+		{`source = "header", property = "X-Status", comparison = "one_of", target = ["${var.status}"]`, true},
+		{`source = "header", property = "X-Status", comparison = "one_of", target = ["pending"]`, false},
+	}
+
+	for _, c := range textCases {
+		r, err := New(bcltest.Block(t, `resource "http_assertion" "a" { `+c.text+` }`))
+		if !assert.Nil(t, err, c.text) {
+			continue
+		}
+
+		err = r.Exec(ctx)
+		if c.valid {
+			assert.Nil(t, err, c.text)
+		} else {
+			assert.NotNil(t, err, c.text)
+		}
+	}
+
+	r, err := New(bcltest.Block(t, `resource "http_assertion" "a" {
+		source = "body"
+		comparison = "one_of"
+		target = ["paid", "refunded"]
+	}`))
+	assert.Nil(t, err)
+	assert.EqualError(t, r.Exec(ctx), `one_of comparison failed, "pending" is not one of ["paid","refunded"]`)
+
+	r, err = New(bcltest.Block(t, `resource "http_assertion" "a" { source = "json_body", property = "tags", comparison = "is_object" }`))
+	assert.Nil(t, err)
+	assert.EqualError(t, r.Exec(bodyContext(body)), "is_object comparison failed, JSON property type is list")
+
+	invalid := []string{
+		`resource "http_assertion" "a" { source = "json_body", property = "name", comparison = "one_of" }`,
+		`resource "http_assertion" "a" { source = "json_body", property = "name", comparison = "one_of", target = [] }`,
+		`resource "http_assertion" "a" { source = "json_body", property = "name", comparison = "one_of", target = "a" }`,
+		`resource "http_assertion" "a" { source = "json_body", property = "name", comparison = "equals", target = ["a"] }`,
+		`resource "http_assertion" "a" { source = "json_body", property = "name", comparison = "length_equals" }`,
+		`resource "http_assertion" "a" { source = "json_body", property = "name", comparison = "length_equals", target = "three" }`,
+		`resource "http_assertion" "a" { source = "body", comparison = "is_string" }`,
+		`resource "http_assertion" "a" { source = "status_code", comparison = "one_of", target = ["200"] }`,
+	}
+
+	for _, text := range invalid {
+		_, err := New(bcltest.Block(t, text))
+		assert.NotNil(t, err, text)
+	}
+}
+
+func TestHeaderAssertions(t *testing.T) {
+	ctx := bodyContext("")
+	ctx.CurrentResponse.Header = http.Header{
+		"Content-Type":  []string{"application/json; charset=UTF-8"},
+		"Cache-Control": []string{"no-store"},
+		"Vary":          []string{"Accept", "Origin"},
+		"X-Empty":       []string{""},
+		"X-Invalid":     []string{"text/html; charset"},
+	}
+	ctx.SetVariable("header", "vary")
+
+	cases := []struct {
+		text  string
+		valid bool
+	}{
+		// header names are case-insensitive
+		{`property = "content-type", comparison = "contains", target = "json"`, true},
+		{`property = "CACHE-CONTROL", comparison = "equals", target = "no-store"`, true},
+		{`property = "${var.header}", comparison = "equals", target = "Accept"`, true},
+		// media types
+		{`property = "Content-Type", attribute = "media_type", comparison = "equals", target = "application/json"`, true},
+		{`property = "Content-Type", attribute = "media_type", comparison = "one_of", target = ["application/json", "text/json"]`, true},
+		{`property = "Content-Type", attribute = "charset", comparison = "equals", target = "UTF-8"`, true},
+		{`property = "Content-Type", comparison = "equals", target = "application/json"`, false},
+		{`property = "X-Invalid", attribute = "media_type", comparison = "equals", target = "text/html"`, false},
+		// existence
+		{`property = "x-empty", comparison = "header_exists"`, true},
+		{`property = "X-Missing", comparison = "header_exists"`, false},
+		{`property = "X-Missing", comparison = "header_absent"`, true},
+		{`property = "x-empty", comparison = "header_absent"`, false},
+		// repeated headers
+		{`property = "Vary", match = "any_value", comparison = "equals", target = "Origin"`, true},
+		{`property = "Vary", match = "all_values", comparison = "one_of", target = ["Accept", "Origin"]`, true},
+		{`property = "Vary", match = "all_values", comparison = "equals", target = "Accept"`, false},
+		{`property = "Vary", match = "count", comparison = "equals", target = "2"`, true},
+		{`property = "X-Missing", match = "count", comparison = "equals", target = "0"`, true},
+		{`property = "X-Missing", match = "any_value", comparison = "is_empty"`, false},
+	}
+
+	for _, c := range cases {
+		r, err := New(bcltest.Block(t, `resource "http_assertion" "a" { source = "header", `+c.text+` }`))
+		if !assert.Nil(t, err, c.text) {
+			continue
+		}
+
+		err = r.Exec(ctx)
+		if c.valid {
+			assert.Nil(t, err, c.text)
+		} else {
+			assert.NotNil(t, err, c.text)
+		}
+	}
+
+	r, err := New(bcltest.Block(t, `resource "http_assertion" "a" {
+		source = "header"
+		property = "vary"
+		match = "all_values"
+		comparison = "equals"
+		target = "Accept"
+	}`))
+	assert.Nil(t, err)
+	assert.EqualError(t, r.Exec(ctx), `value 2 of 2 at "vary": equals comparison failed, "Origin" != "Accept"`)
+
+	r, err = New(bcltest.Block(t, `resource "http_assertion" "a" { source = "header", property = "vary", comparison = "header_absent" }`))
+	assert.Nil(t, err)
+	assert.EqualError(t, r.Exec(ctx), `header_absent comparison failed, vary header is set to "Accept, Origin"`)
+
+	_, err = New(bcltest.Block(t, `resource "http_assertion" "a" { source = "header", property = "Vary", match = "any_value", comparison = "header_exists" }`))
+	assert.NotNil(t, err)
+
+	// json_body modes are not header modes
+	_, err = New(bcltest.Block(t, `resource "http_assertion" "a" { source = "header", property = "Vary", match = "any", comparison = "equals", target = "Origin" }`))
+	assert.EqualError(t, err, "invalid `match` value \"any\", allowed values are all_values, any_value, count")
+}
@@ -0,0 +1,98 @@
+package http_assertion
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+
+	"github.com/bluebookrun/bluebook/interpolator"
+	"github.com/bluebookrun/bluebook/resource"
+)
+
+// compileSchema compiles inline schema, or schema file if path is set.
+// Schemas without $schema keyword use draft 2020-12.
+func compileSchema(schema string, path string) (*jsonschema.Schema, error) {
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft2020
+
+	if path != "" {
+		return compiler.Compile(path)
+	}
+
+	// relative $refs of inline schemas are resolved from the working directory
+	if err := compiler.AddResource("inline-schema.json", strings.NewReader(schema)); err != nil {
+		return nil, err
+	}
+	return compiler.Compile("inline-schema.json")
+}
+
+// assertSchema validates JSON body, or its property if set, with JSON
+// schema and reports every violation
+func (r *Resource) assertSchema(ctx *resource.ExecutionContext) error {
+	schema := r.schema
+	if schema == nil {
+		target, err := interpolator.Eval(r.target, ctx)
+		if err != nil {
+			return r.errorf("%s", err.Error())
+		}
+
+		path, err := interpolator.Eval(r.schemaFile, ctx)
+		if err != nil {
+			return r.errorf("%s", err.Error())
+		}
+
+		schema, err = compileSchema(target, path)
+		if err != nil {
+			return r.errorf("invalid JSON schema: %s", err.Error())
+		}
+	}
+
+	document, err := resource.DecodeJSON(ctx.CurrentResponseBody)
+	if err != nil {
+		return r.errorf("unable to decode JSON body: %s", err.Error())
+	}
+
+	if r.property != "" {
+		path, err := r.jsonPath(ctx)
+		if err != nil {
+			return err
+		}
+
+		document, err = path.Get(document)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = schema.Validate(document)
+	if err == nil {
+		return nil
+	}
+
+	validationError, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return r.errorf("matches_schema comparison failed, %s", err.Error())
+	}
+
+	violations := schemaViolations(validationError, make([]string, 0))
+	return r.errorf("matches_schema comparison failed, %d violations:\n    - %s",
+		len(violations), strings.Join(violations, "\n    - "))
+}
+
+// schemaViolations returns leaf errors prefixed with JSON pointers of
+// invalid values
+func schemaViolations(err *jsonschema.ValidationError, violations []string) []string {
+	if len(err.Causes) == 0 {
+		pointer := err.InstanceLocation
+		if pointer == "" {
+			pointer = "/"
+		}
+		return append(violations, fmt.Sprintf("%s: %s", pointer, err.Message))
+	}
+
+	for _, cause := range err.Causes {
+		violations = schemaViolations(cause, violations)
+	}
+	return violations
+}
@@ -0,0 +1,124 @@
+package http_assertion
+
+import (
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bluebookrun/bluebook/bcl/bcltest"
+	"github.com/bluebookrun/bluebook/resource"
+)
+
+func bodyContext(body string) *resource.ExecutionContext {
+	ctx := resource.NewExecutionContext()
+	ctx.CurrentResponse = &http.Response{}
+	ctx.CurrentResponseBody = []byte(body)
+	return ctx
+}
+
+func TestMatchesSchema(t *testing.T) {
+	r, err := New(bcltest.Block(t, `resource "http_assertion" "user" {
+		source = "json_body"
+		comparison = "matches_schema"
+		target = <<<EOF
+{
+	"type": "object",
+	"required": ["id", "email"],
+	"properties": {
+		"id": {"type": "integer", "minimum": 1},
+		"email": {"type": "string", "format": "email"},
+		"tags": {"type": "array", "items": {"type": "string"}}
+	},
+	"additionalProperties": false
+}
+EOF
+	}`))
+	assert.Nil(t, err)
+	assert.NotNil(t, r.schema)
+
+	assert.Nil(t, r.Exec(bodyContext(`{"id": 9007199254740993, "email": "a@example.com", "tags": ["a"]}`)))
+
+	err = r.Exec(bodyContext(`{"id": 0, "tags": ["a", 1], "name": "x"}`))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "matches_schema comparison failed, 4 violations:")
+		assert.Contains(t, err.Error(), "\n    - /: missing properties: 'email'")
+		assert.Contains(t, err.Error(), "\n    - /id: must be >= 1 but found 0")
+		assert.Contains(t, err.Error(), "\n    - /tags/1: expected string, but got number")
+		assert.Contains(t, err.Error(), "additionalProperties 'name' not allowed")
+	}
+
+	assert.NotNil(t, r.Exec(bodyContext(`not json`)))
+}
+
+func TestMatchesSchemaFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "schema")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "item.json"), []byte(`{
+		"type": "object",
+		"required": ["id"],
+		"properties": {"id": {"type": "string"}}
+	}`), 0644))
+	assert.Nil(t, ioutil.WriteFile(filepath.Join(dir, "items.json"), []byte(`{
+		"$schema": "https://json-schema.org/draft/2020-12/schema",
+		"type": "array",
+		"items": {"$ref": "item.json"}
+	}`), 0644))
+
+	// root arrays are supported
+	r, err := New(bcltest.Block(t, `resource "http_assertion" "items" {
+		source = "json_body"
+		comparison = "matches_schema"
+		schema_file = "`+filepath.Join(dir, "items.json")+`"
+	}`))
+	assert.Nil(t, err)
+	assert.Nil(t, r.Exec(bodyContext(`[{"id": "a"}, {"id": "b"}]`)))
+
+	err = r.Exec(bodyContext(`[{"id": "a"}, {"id": 2}]`))
+	if assert.NotNil(t, err) {
+		assert.Contains(t, err.Error(), "1 violations:\n    - /1/id: expected string, but got number")
+	}
+
+	// property selects the validated value, paths are interpolated
+	r, err = New(bcltest.Block(t, `resource "http_assertion" "item" {
+		source = "json_body"
+		property = "data"
+		comparison = "matches_schema"
+		schema_file = "${var.schemas}/item.json"
+	}`))
+	assert.Nil(t, err)
+	assert.Nil(t, r.schema)
+
+	ctx := bodyContext(`{"data": {"id": "a"}}`)
+	ctx.SetVariable("schemas", dir)
+	assert.Nil(t, r.Exec(ctx))
+
+	ctx = bodyContext(`{"data": {}}`)
+	ctx.SetVariable("schemas", dir)
+	assert.NotNil(t, r.Exec(ctx))
+}
+
+func TestMatchesSchemaValidation(t *testing.T) {
+	invalid := []string{
+		`resource "http_assertion" "a" { source = "json_body", comparison = "matches_schema" }`,
+		`resource "http_assertion" "a" { source = "json_body", comparison = "matches_schema", target = "{}", schema_file = "a.json" }`,
+		`resource "http_assertion" "a" { source = "json_body", comparison = "matches_schema", target = "not json" }`,
+		`resource "http_assertion" "a" { source = "json_body", comparison = "matches_schema", target = <<<EOF
+{"type": 5}
+EOF
+}`,
+		`resource "http_assertion" "a" { source = "json_body", comparison = "matches_schema", schema_file = "missing.json" }`,
+		`resource "http_assertion" "a" { source = "body", comparison = "matches_schema", target = "{}" }`,
+		`resource "http_assertion" "a" { source = "json_body", property = "a", comparison = "equals", target = "a", schema_file = "a.json" }`,
+	}
+
+	for _, text := range invalid {
+		_, err := New(bcltest.Block(t, text))
+		assert.NotNil(t, err, text)
+	}
+}
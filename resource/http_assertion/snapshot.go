@@ -0,0 +1,187 @@
+package http_assertion
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bluebookrun/bluebook/interpolator"
+	"github.com/bluebookrun/bluebook/resource"
+)
+
+// SnapshotDir is where matches_snapshot comparison stores snapshots,
+// relative to the working directory
+var SnapshotDir = "__snapshots__"
+
+// ignored values are replaced in both the snapshot and the response
+const ignoredValue = "<ignored>"
+
+// assertSnapshot compares the body with its snapshot. Missing and changed
+// snapshots are written only when snapshots are updated.
+func (r *Resource) assertSnapshot(ctx *resource.ExecutionContext) error {
+	name, err := r.snapshotName(ctx)
+	if err != nil {
+		return err
+	}
+
+	var actual []byte
+	var document interface{}
+	path := filepath.Join(SnapshotDir, name+".txt")
+
+	if r.source == "json_body" {
+		path = filepath.Join(SnapshotDir, name+".json")
+
+		document, err = r.snapshotDocument(ctx, ctx.CurrentResponseBody)
+		if err != nil {
+			return r.errorf("unable to decode JSON body: %s", err.Error())
+		}
+
+		actual, err = encodeSnapshot(document)
+		if err != nil {
+			return r.errorf("%s", err.Error())
+		}
+	} else {
+		actual = ctx.CurrentResponseBody
+	}
+
+	stored, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		if ctx.UpdateSnapshots {
+			return r.writeSnapshot(path, actual, "written")
+		}
+		return r.errorf("snapshot %s does not exist, run with --update-snapshots", path)
+	}
+	if err != nil {
+		return r.errorf("unable to read snapshot: %s", err.Error())
+	}
+
+	var diff string
+	if r.source == "json_body" {
+		expected, err := r.snapshotDocument(ctx, stored)
+		if err != nil {
+			return r.errorf("unable to decode snapshot %s: %s", path, err.Error())
+		}
+
+		if diffs := jsonDiff(expected, document, "$", nil); len(diffs) > 0 {
+			diff = fmt.Sprintf("%d differences from %s:%s", len(diffs), path, formatDiffs(diffs))
+		}
+	} else if !bytes.Equal(stored, actual) {
+		diff = textDiff(path, string(stored), string(actual))
+	}
+
+	if diff == "" {
+		return nil
+	}
+
+	if ctx.UpdateSnapshots {
+		return r.writeSnapshot(path, actual, "updated")
+	}
+	return r.errorf("matches_snapshot comparison failed, %s", diff)
+}
+
+// snapshotName returns interpolated snapshot name. The default name is
+// made of the test, step and assertion names, so an assertion used by
+// several steps has a snapshot for each of them. Names are file names in
+// SnapshotDir and can't contain path separators.
+func (r *Resource) snapshotName(ctx *resource.ExecutionContext) (string, error) {
+	name := r.snapshot
+	if name == "" {
+		parts := make([]string, 0, 3)
+		for _, ref := range []string{ctx.CurrentTest, ctx.CurrentStep} {
+			if ref != "" {
+				parts = append(parts, ref[strings.Index(ref, ".")+1:])
+			}
+		}
+		name = strings.Join(append(parts, string(r.Node.Name.Text)), ".")
+	} else {
+		interpolated, err := interpolator.Eval(name, ctx)
+		if err != nil {
+			return "", r.errorf("%s", err.Error())
+		}
+		name = interpolated
+	}
+
+	if name == "" || strings.ContainsAny(name, `/\`) || strings.Contains(name, "..") {
+		return "", r.errorf("invalid snapshot name %q", name)
+	}
+	return name, nil
+}
+
+// snapshotDocument decodes JSON data, or its property if set, and
+// replaces ignored values
+func (r *Resource) snapshotDocument(ctx *resource.ExecutionContext, data []byte) (interface{}, error) {
+	document, err := resource.DecodeJSON(data)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.property != "" {
+		path, err := r.jsonPath(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		document, err = path.Get(document)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	for _, path := range r.ignore {
+		document = path.Replace(document, func(interface{}) interface{} {
+			return ignoredValue
+		})
+	}
+	return document, nil
+}
+
+func (r *Resource) writeSnapshot(path string, data []byte, action string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return r.errorf("unable to write snapshot: %s", err.Error())
+	}
+
+	if err := ioutil.WriteFile(path, data, 0644); err != nil {
+		return r.errorf("unable to write snapshot: %s", err.Error())
+	}
+
+	fmt.Printf("    snapshot %s %s\n", path, action)
+	return nil
+}
+
+// encodeSnapshot formats JSON document with sorted keys, so snapshots
+// are stable and easy to review
+func encodeSnapshot(document interface{}) ([]byte, error) {
+	b := &bytes.Buffer{}
+	encoder := json.NewEncoder(b)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(document); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+// textDiff reports the first line that differs
+func textDiff(path string, expected string, actual string) string {
+	expectedLines := strings.Split(expected, "\n")
+	actualLines := strings.Split(actual, "\n")
+
+	line := 0
+	for line < len(expectedLines) && line < len(actualLines) && expectedLines[line] == actualLines[line] {
+		line++
+	}
+
+	at := func(lines []string) string {
+		if line < len(lines) {
+			return fmt.Sprintf("%q", lines[line])
+		}
+		return "end of body"
+	}
+
+	return fmt.Sprintf("body differs from %s at line %d:\n    - %s\n    + %s",
+		path, line+1, at(expectedLines), at(actualLines))
+}
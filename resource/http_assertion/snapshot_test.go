@@ -0,0 +1,192 @@
+package http_assertion
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bluebookrun/bluebook/bcl/bcltest"
+)
+
+func snapshotDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "snapshots")
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	previous := SnapshotDir
+	SnapshotDir = filepath.Join(dir, "__snapshots__")
+	t.Cleanup(func() {
+		SnapshotDir = previous
+		os.RemoveAll(dir)
+	})
+	return SnapshotDir
+}
+
+func TestMatchesSnapshot(t *testing.T) {
+	dir := snapshotDir(t)
+
+	r, err := New(bcltest.Block(t, `resource "http_assertion" "user" {
+		source = "json_body"
+		comparison = "matches_snapshot"
+		ignore = ["$.id", "$..created_at"]
+	}`))
+	assert.Nil(t, err)
+
+	// missing snapshot fails unless snapshots are updated
+	body := `{"name": "a<b>", "id": 17, "tags": [{"name": "x", "created_at": "2020-01-01"}], "score": 1.5}`
+	ctx := bodyContext(body)
+	assert.EqualError(t, r.Exec(ctx),
+		"snapshot "+filepath.Join(dir, "user.json")+" does not exist, run with --update-snapshots")
+
+	ctx.UpdateSnapshots = true
+	assert.Nil(t, r.Exec(ctx))
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "user.json"))
+	assert.Nil(t, err)
+	assert.Equal(t, `{
+  "id": "<ignored>",
+  "name": "a<b>",
+  "score": 1.5,
+  "tags": [
+    {
+      "created_at": "<ignored>",
+      "name": "x"
+    }
+  ]
+}
+`, string(data))
+
+	// ignored values and key order don't matter
+	assert.Nil(t, r.Exec(bodyContext(`{"tags": [{"created_at": "2021-05-05", "name": "x"}], "score": 1.50, "id": 18, "name": "a<b>"}`)))
+
+	ctx = bodyContext(`{"name": "b", "id": 17, "tags": [{"name": "x", "created_at": "now"}, "y"], "score": "1.5", "extra": null}`)
+	err = r.Exec(ctx)
+	if assert.NotNil(t, err) {
+		assert.Equal(t, `matches_snapshot comparison failed, 4 differences from `+filepath.Join(dir, "user.json")+`:
+    - $.extra: unexpected null
+    - $.name: expected "a<b>", got "b"
+    - $.score: expected 1.5, got "1.5"
+    - $.tags[1]: unexpected "y"`, err.Error())
+	}
+
+	// differences are written when snapshots are updated
+	ctx.UpdateSnapshots = true
+	assert.Nil(t, r.Exec(ctx))
+	ctx.UpdateSnapshots = false
+	assert.Nil(t, r.Exec(ctx))
+}
+
+func TestMatchesSnapshotNames(t *testing.T) {
+	dir := snapshotDir(t)
+
+	r, err := New(bcltest.Block(t, `resource "http_assertion" "items" {
+		source = "json_body"
+		property = "$.items"
+		comparison = "matches_snapshot"
+		snapshot = "items-${var.page}"
+	}`))
+	assert.Nil(t, err)
+
+	for _, page := range []string{"1", "2"} {
+		ctx := bodyContext(`{"items": [` + page + `], "page": ` + page + `}`)
+		ctx.SetVariable("page", page)
+		ctx.UpdateSnapshots = true
+		assert.Nil(t, r.Exec(ctx))
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "items-"+page+".json"))
+		assert.Nil(t, err)
+		assert.Equal(t, "[\n  "+page+"\n]\n", string(data))
+	}
+}
+
+func TestMatchesSnapshotDefaultNames(t *testing.T) {
+	dir := snapshotDir(t)
+
+	r, err := New(bcltest.Block(t, `resource "http_assertion" "page" {
+		source = "body"
+		comparison = "matches_snapshot"
+	}`))
+	assert.Nil(t, err)
+
+	// steps sharing the assertion have their own snapshots
+	for _, step := range []string{"http_step.first", "http_step.second"} {
+		ctx := bodyContext(step)
+		ctx.CurrentTest = "http_test.pages"
+		ctx.CurrentStep = step
+		ctx.UpdateSnapshots = true
+		assert.Nil(t, r.Exec(ctx))
+
+		data, err := ioutil.ReadFile(filepath.Join(dir, "pages."+step[len("http_step."):]+".page.txt"))
+		assert.Nil(t, err)
+		assert.Equal(t, step, string(data))
+	}
+}
+
+func TestMatchesSnapshotInvalidNames(t *testing.T) {
+	snapshotDir(t)
+
+	r, err := New(bcltest.Block(t, `resource "http_assertion" "a" {
+		source = "body"
+		comparison = "matches_snapshot"
+		snapshot = "${var.name}"
+	}`))
+	assert.Nil(t, err)
+
+	for _, name := range []string{"../a", "a/b", `a\b`, "..", ""} {
+		ctx := bodyContext("a")
+		ctx.SetVariable("name", name)
+		ctx.UpdateSnapshots = true
+		assert.EqualError(t, r.Exec(ctx), fmt.Sprintf("invalid snapshot name %q", name))
+	}
+}
+
+func TestMatchesSnapshotText(t *testing.T) {
+	dir := snapshotDir(t)
+
+	r, err := New(bcltest.Block(t, `resource "http_assertion" "page" {
+		source = "body"
+		comparison = "matches_snapshot"
+	}`))
+	assert.Nil(t, err)
+
+	ctx := bodyContext("line 1\nline 2\n")
+	ctx.UpdateSnapshots = true
+	assert.Nil(t, r.Exec(ctx))
+	assert.Nil(t, r.Exec(bodyContext("line 1\nline 2\n")))
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "page.txt"))
+	assert.Nil(t, err)
+	assert.Equal(t, "line 1\nline 2\n", string(data))
+
+	assert.EqualError(t, r.Exec(bodyContext("line 1\nline two\n")),
+		`matches_snapshot comparison failed, body differs from `+filepath.Join(dir, "page.txt")+` at line 2:
+    - "line 2"
+    + "line two"`)
+
+	assert.EqualError(t, r.Exec(bodyContext("line 1")),
+		`matches_snapshot comparison failed, body differs from `+filepath.Join(dir, "page.txt")+` at line 2:
+    - "line 2"
+    + end of body`)
+}
+
+func TestMatchesSnapshotValidation(t *testing.T) {
+	invalid := []string{
+		`resource "http_assertion" "a" { source = "json_body", comparison = "matches_snapshot", target = "a" }`,
+		`resource "http_assertion" "a" { source = "body", comparison = "matches_snapshot", ignore = ["$.id"] }`,
+		`resource "http_assertion" "a" { source = "json_body", comparison = "matches_snapshot", ignore = ["$[?(@.a =)]"] }`,
+		`resource "http_assertion" "a" { source = "json_body", comparison = "matches_snapshot", ignore = "$.id" }`,
+		`resource "http_assertion" "a" { source = "json_body", property = "a", comparison = "is_null", snapshot = "a" }`,
+		`resource "http_assertion" "a" { source = "json_body", property = "$[*]", match = "all", comparison = "matches_snapshot" }`,
+		`resource "http_assertion" "a" { source = "header", property = "a", comparison = "matches_snapshot" }`,
+	}
+
+	for _, text := range invalid {
+		_, err := New(bcltest.Block(t, text))
+		assert.NotNil(t, err, text)
+	}
+}
@@ -0,0 +1,190 @@
+package http_auth
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/bluebookrun/bluebook/bcl"
+)
+
+// Authentication types
+const (
+	TypeNone     = "none"      // disables authentication inherited from http_test
+	TypeBasic    = "basic"     // basic authentication
+	TypeBearer   = "bearer"    // bearer token
+	TypeDigest   = "digest"    // digest access authentication, answers server challenges
+	TypeHMAC     = "hmac"      // HMAC signature of a canonical string
+	TypeAWSSigV4 = "aws_sigv4" // AWS signature version 4
+)
+
+// Auth configures authentication of requests. It can be set on http_test
+// and on http_step, step authentication replaces the test one.
+type Auth struct {
+	Type       string
+	Inputs     map[string]string // interpolated for every request
+	Components []string          // parts of the canonical string of hmac signatures
+}
+
+// Signer adds authentication to requests
+type Signer interface {
+	// Sign authenticates the request, body is the request body
+	Sign(req *http.Request, body []byte) error
+}
+
+// Challenger is implemented by signers that answer authentication
+// challenges of the server, e.g. digest
+type Challenger interface {
+	// Challenge reads the challenge from the response and returns true
+	// if the request should be sent again
+	Challenge(resp *http.Response) bool
+}
+
+// replaced in tests
+var now = time.Now
+
+type inputSpec struct {
+	required []string
+	optional []string
+}
+
+var specs = map[string]inputSpec{
+	TypeNone:     {},
+	TypeBasic:    {required: []string{"username"}, optional: []string{"password"}},
+	TypeBearer:   {required: []string{"token"}},
+	TypeDigest:   {required: []string{"username"}, optional: []string{"password"}},
+	TypeHMAC:     {required: []string{"secret"}, optional: []string{"algorithm", "encoding", "header", "prefix"}},
+	TypeAWSSigV4: {required: []string{"access_key", "secret_key", "region", "service"}, optional: []string{"session_token"}},
+}
+
+// Types returns supported authentication types
+func Types() []string {
+	types := make([]string, 0, len(specs))
+	for t := range specs {
+		types = append(types, t)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Parse parses `auth "type" { ... }` block
+func Parse(block *bcl.BlockNode) (*Auth, error) {
+	a := &Auth{
+		Type:   string(block.Name.Text),
+		Inputs: make(map[string]string),
+	}
+
+	if len(block.Driver.Text) != 0 || len(block.Blocks) != 0 {
+		return nil, fmt.Errorf("invalid `auth` block, e.g. auth \"basic\" { ... }")
+	}
+
+	spec, ok := specs[a.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown auth type %q, supported types are %s", a.Type, strings.Join(Types(), ", "))
+	}
+
+	for _, expression := range block.Expressions {
+		field := string(expression.Field.Text)
+
+		if a.Type == TypeHMAC && field == "components" {
+			components, err := parseComponents(expression)
+			if err != nil {
+				return nil, err
+			}
+			a.Components = components
+			continue
+		}
+
+		if !contains(spec.required, field) && !contains(spec.optional, field) {
+			return nil, fmt.Errorf("unknown %s auth input %q", a.Type, field)
+		}
+
+		value, err := expression.ValueAsString()
+		if err != nil {
+			return nil, err
+		}
+		a.Inputs[field] = value
+	}
+
+	for _, field := range spec.required {
+		if _, ok := a.Inputs[field]; !ok {
+			return nil, fmt.Errorf("%s auth: `%s` is required", a.Type, field)
+		}
+	}
+
+	if a.Type == TypeHMAC {
+		if err := a.validateHMAC(); err != nil {
+			return nil, err
+		}
+	}
+
+	return a, nil
+}
+
+// Signer evaluates inputs with eval and returns a signer for requests of
+// one step. It returns nil if requests are not authenticated.
+func (a *Auth) Signer(eval func(string) (string, error)) (Signer, error) {
+	if a == nil || a.Type == TypeNone {
+		return nil, nil
+	}
+
+	inputs := make(map[string]string)
+	for name, text := range a.Inputs {
+		value, err := eval(text)
+		if err != nil {
+			return nil, err
+		}
+		inputs[name] = value
+	}
+
+	switch a.Type {
+	case TypeBasic:
+		return &basicSigner{username: inputs["username"], password: inputs["password"]}, nil
+	case TypeBearer:
+		return &bearerSigner{token: inputs["token"]}, nil
+	case TypeDigest:
+		return &digestSigner{username: inputs["username"], password: inputs["password"]}, nil
+	case TypeHMAC:
+		return newHMACSigner(inputs, a.Components), nil
+	case TypeAWSSigV4:
+		return &sigV4Signer{
+			accessKey:    inputs["access_key"],
+			secretKey:    inputs["secret_key"],
+			sessionToken: inputs["session_token"],
+			region:       inputs["region"],
+			service:      inputs["service"],
+		}, nil
+	}
+
+	return nil, fmt.Errorf("unknown auth type %q", a.Type)
+}
+
+type basicSigner struct {
+	username string
+	password string
+}
+
+func (s *basicSigner) Sign(req *http.Request, body []byte) error {
+	req.SetBasicAuth(s.username, s.password)
+	return nil
+}
+
+type bearerSigner struct {
+	token string
+}
+
+func (s *bearerSigner) Sign(req *http.Request, body []byte) error {
+	req.Header.Set("Authorization", "Bearer "+s.token)
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,283 @@
+package http_auth
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bluebookrun/bluebook/bcl/bcltest"
+)
+
+// newSigner parses auth block, ${var.password} is interpolated as secret
+func newSigner(t *testing.T, text string) Signer {
+	auth, err := Parse(bcltest.Block(t, text))
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+
+	signer, err := auth.Signer(func(text string) (string, error) {
+		return strings.Replace(text, "${var.password}", "secret", -1), nil
+	})
+	if !assert.Nil(t, err) {
+		t.FailNow()
+	}
+	return signer
+}
+
+// send signs the request and answers a challenge, the same as http_step
+func send(t *testing.T, signer Signer, method string, url string, body string) *http.Response {
+	newRequest := func() *http.Request {
+		req, err := http.NewRequest(method, url, strings.NewReader(body))
+		assert.Nil(t, err)
+		assert.Nil(t, signer.Sign(req, []byte(body)))
+		return req
+	}
+
+	resp, err := http.DefaultClient.Do(newRequest())
+	assert.Nil(t, err)
+
+	if challenger, ok := signer.(Challenger); ok && challenger.Challenge(resp) {
+		io.Copy(ioutil.Discard, resp.Body)
+		resp.Body.Close()
+
+		resp, err = http.DefaultClient.Do(newRequest())
+		assert.Nil(t, err)
+	}
+
+	resp.Body.Close()
+	return resp
+}
+
+func TestParse(t *testing.T) {
+	valid := []string{
+		`auth "none" { }`,
+		`auth "basic" { username = "a", password = "b" }`,
+		`auth "bearer" { token = "${var.token}" }`,
+		`auth "digest" { username = "a" }`,
+		`auth "hmac" { secret = "s", algorithm = "sha1", encoding = "base64", components = ["method", "header:x-request-id"] }`,
+		`auth "aws_sigv4" { access_key = "a", secret_key = "b", region = "us-east-1", service = "execute-api" }`,
+	}
+
+	for _, text := range valid {
+		_, err := Parse(bcltest.Block(t, text))
+		assert.Nil(t, err, text)
+	}
+
+	invalid := []string{
+		`auth { username = "a" }`,
+		`auth "oauth" { }`,
+		`auth "basic" { password = "b" }`,
+		`auth "basic" { username = "a", token = "b" }`,
+		`auth "bearer" { token = "a", components = ["method"] }`,
+		`auth "hmac" { secret = "s", algorithm = "md4" }`,
+		`auth "hmac" { secret = "s", encoding = "base32" }`,
+		`auth "hmac" { secret = "s", components = ["url"] }`,
+		`auth "hmac" { secret = "s", components = [] }`,
+		`auth "aws_sigv4" { access_key = "a", secret_key = "b", region = "us-east-1" }`,
+		`auth "basic" { username = "a" nested { } }`,
+	}
+
+	for _, text := range invalid {
+		_, err := Parse(bcltest.Block(t, text))
+		assert.NotNil(t, err, text)
+	}
+}
+
+func TestNone(t *testing.T) {
+	var auth *Auth
+	signer, err := auth.Signer(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, signer)
+
+	auth, err = Parse(bcltest.Block(t, `auth "none" { }`))
+	assert.Nil(t, err)
+	signer, err = auth.Signer(nil)
+	assert.Nil(t, err)
+	assert.Nil(t, signer)
+}
+
+func TestBasicAndBearer(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		username, password, ok := r.BasicAuth()
+		if ok && username == "user" && password == "secret" {
+			return
+		}
+		if r.Header.Get("Authorization") == "Bearer token-secret" {
+			return
+		}
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		auth   string
+		status int
+	}{
+		{`auth "basic" { username = "user", password = "${var.password}" }`, 200},
+		{`auth "basic" { username = "user", password = "wrong" }`, 401},
+		{`auth "bearer" { token = "token-${var.password}" }`, 200},
+		{`auth "bearer" { token = "wrong" }`, 401},
+	}
+
+	for _, c := range cases {
+		resp := send(t, newSigner(t, c.auth), "GET", server.URL, "")
+		assert.Equal(t, c.status, resp.StatusCode, c.auth)
+	}
+}
+
+// digestServer checks MD5 digest with qop=auth, nonce counts must grow
+func digestServer(t *testing.T) *httptest.Server {
+	const realm = "test@example.com"
+	const nonce = "dcd98b7102dd2f0e8b11d0f600bfb0c093"
+	lastCount := ""
+
+	h := func(parts ...string) string {
+		sum := md5.Sum([]byte(strings.Join(parts, ":")))
+		return hex.EncodeToString(sum[:])
+	}
+
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := r.Header.Get("Authorization")
+		if strings.HasPrefix(header, "Digest ") {
+			p := parseChallenge(header[7:])
+			ha1 := h(p["username"], realm, "secret")
+			ha2 := h(r.Method, p["uri"])
+			expected := h(ha1, nonce, p["nc"], p["cnonce"], p["qop"], ha2)
+
+			if p["response"] == expected && p["uri"] == r.URL.RequestURI() &&
+				p["opaque"] == "5ccc069c" && p["nc"] > lastCount {
+				lastCount = p["nc"]
+				return
+			}
+		}
+
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(
+			`Digest realm="%s", qop="auth,auth-int", nonce="%s", opaque="5ccc069c"`, realm, nonce))
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+}
+
+func TestDigest(t *testing.T) {
+	server := digestServer(t)
+	defer server.Close()
+
+	signer := newSigner(t, `auth "digest" { username = "Mufasa", password = "${var.password}" }`)
+
+	// the first request answers the challenge, the next ones reuse it
+	for i := 0; i < 3; i++ {
+		resp := send(t, signer, "GET", server.URL+"/dir/index.html?a=b", "")
+		assert.Equal(t, 200, resp.StatusCode)
+	}
+
+	signer = newSigner(t, `auth "digest" { username = "Mufasa", password = "wrong" }`)
+	resp := send(t, signer, "GET", server.URL, "")
+	assert.Equal(t, 401, resp.StatusCode)
+}
+
+func TestParseChallenge(t *testing.T) {
+	assert.Equal(t, map[string]string{
+		"realm":     "a, b",
+		"qop":       "auth",
+		"nonce":     `x"y`,
+		"algorithm": "SHA-256",
+	}, parseChallenge(`realm="a, b", qop=auth,nonce="x\"y" , algorithm=SHA-256`))
+}
+
+func TestHMAC(t *testing.T) {
+	now = func() time.Time {
+		return time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	}
+	defer func() { now = time.Now }()
+
+	var header, date, canonical string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		sum := sha256.Sum256(body)
+
+		header = r.Header.Get("Authorization")
+		date = r.Header.Get("Date")
+		canonical = strings.Join([]string{r.Method, r.URL.RequestURI(), date, hex.EncodeToString(sum[:])}, "\n")
+
+		mac := hmac.New(sha256.New, []byte("secret"))
+		mac.Write([]byte(canonical))
+		if header != "HMAC key-1:"+hex.EncodeToString(mac.Sum(nil)) {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	signer := newSigner(t, `auth "hmac" { secret = "${var.password}", prefix = "HMAC key-1:" }`)
+	resp := send(t, signer, "POST", server.URL+"/orders?id=1", `{"id": 1}`)
+	assert.Equal(t, 200, resp.StatusCode)
+	assert.Equal(t, "Thu, 02 Jan 2020 03:04:05 GMT", date)
+
+	signer = newSigner(t, `auth "hmac" {
+		secret = "secret"
+		algorithm = "sha1"
+		encoding = "base64"
+		header = "X-Signature"
+		components = ["method", "host", "header:x-request-id", "body"]
+	}`)
+	req, _ := http.NewRequest("PUT", "http://example.com/a", nil)
+	req.Header.Set("X-Request-Id", "42")
+	assert.Nil(t, signer.Sign(req, []byte("data")))
+	assert.Equal(t, "", req.Header.Get("Date"))
+	assert.Equal(t, "PUT\nexample.com\n42\ndata", canonicalString(req, []byte("data"), []string{"method", "host", "header:x-request-id", "body"}))
+	assert.Equal(t, "sbI4cWceA+e4jb2jiSFX5g8r9RM=", req.Header.Get("X-Signature"))
+}
+
+func TestSigV4(t *testing.T) {
+	now = func() time.Time {
+		return time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+	}
+	defer func() { now = time.Now }()
+
+	// get-vanilla from the AWS signature version 4 test suite
+	signer := newSigner(t, `auth "aws_sigv4" {
+		access_key = "AKIDEXAMPLE"
+		secret_key = "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"
+		region = "us-east-1"
+		service = "service"
+	}`)
+
+	req, _ := http.NewRequest("GET", "https://example.amazonaws.com/", nil)
+	assert.Nil(t, signer.Sign(req, nil))
+	assert.Equal(t, "20150830T123600Z", req.Header.Get("X-Amz-Date"))
+	assert.Equal(t, "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, "+
+		"SignedHeaders=host;x-amz-date, "+
+		"Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31", req.Header.Get("Authorization"))
+
+	// signing again replaces the signature
+	assert.Nil(t, signer.Sign(req, nil))
+	assert.Equal(t, 1, len(req.Header.Values("Authorization")))
+	assert.True(t, strings.HasSuffix(req.Header.Get("Authorization"), "5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"))
+
+	// session token and payload hash of S3 are signed
+	signer = newSigner(t, `auth "aws_sigv4" {
+		access_key = "a"
+		secret_key = "b"
+		session_token = "token"
+		region = "eu-west-1"
+		service = "s3"
+	}`)
+	req, _ = http.NewRequest("PUT", "https://bucket.s3.amazonaws.com/a%20b.txt?z=1&a=2", bytes.NewReader([]byte("data")))
+	assert.Nil(t, signer.Sign(req, []byte("data")))
+	assert.Equal(t, "token", req.Header.Get("X-Amz-Security-Token"))
+	assert.Equal(t, sha256Hex([]byte("data")), req.Header.Get("X-Amz-Content-Sha256"))
+	assert.Contains(t, req.Header.Get("Authorization"), "SignedHeaders=host;x-amz-content-sha256;x-amz-date;x-amz-security-token,")
+	assert.Equal(t, "/a%20b.txt", (&sigV4Signer{service: "s3"}).canonicalURI(req.URL))
+	assert.Equal(t, "/a%2520b.txt", (&sigV4Signer{service: "execute-api"}).canonicalURI(req.URL))
+	assert.Equal(t, "a=2&z=1", canonicalQuery(req.URL))
+}
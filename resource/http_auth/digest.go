@@ -0,0 +1,193 @@
+package http_auth
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+)
+
+// digestSigner implements digest access authentication (RFC 7616).
+// The first request is sent without credentials, the challenge of the
+// server is kept for the following requests of the step.
+type digestSigner struct {
+	username  string
+	password  string
+	challenge map[string]string // nil until the server sends a challenge
+	count     int               // nonce count
+}
+
+func (s *digestSigner) Challenge(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusUnauthorized {
+		return false
+	}
+
+	for _, header := range resp.Header.Values("WWW-Authenticate") {
+		if len(header) < 7 || !strings.EqualFold(header[:7], "digest ") {
+			continue
+		}
+
+		challenge := parseChallenge(header[7:])
+		if challenge["nonce"] == "" {
+			continue
+		}
+
+		s.challenge = challenge
+		s.count = 0
+		return true
+	}
+
+	return false
+}
+
+func (s *digestSigner) Sign(req *http.Request, body []byte) error {
+	if s.challenge == nil {
+		return nil
+	}
+
+	algorithm := s.challenge["algorithm"]
+	if algorithm == "" {
+		algorithm = "MD5"
+	}
+
+	var newHash func() hash.Hash
+	switch strings.TrimSuffix(strings.ToUpper(algorithm), "-SESS") {
+	case "MD5":
+		newHash = md5.New
+	case "SHA-256":
+		newHash = sha256.New
+	default:
+		return fmt.Errorf("unsupported digest algorithm %q", algorithm)
+	}
+
+	h := func(parts ...string) string {
+		d := newHash()
+		d.Write([]byte(strings.Join(parts, ":")))
+		return hex.EncodeToString(d.Sum(nil))
+	}
+
+	realm := s.challenge["realm"]
+	nonce := s.challenge["nonce"]
+	uri := req.URL.RequestURI()
+
+	s.count++
+	nc := fmt.Sprintf("%08x", s.count)
+	cnonce, err := newNonce()
+	if err != nil {
+		return err
+	}
+
+	ha1 := h(s.username, realm, s.password)
+	if strings.HasSuffix(strings.ToUpper(algorithm), "-SESS") {
+		ha1 = h(ha1, nonce, cnonce)
+	}
+
+	qop := selectQop(s.challenge["qop"])
+
+	ha2 := h(req.Method, uri)
+	if qop == "auth-int" {
+		ha2 = h(req.Method, uri, h(string(body)))
+	}
+
+	var response string
+	if qop == "" {
+		response = h(ha1, nonce, ha2)
+	} else {
+		response = h(ha1, nonce, nc, cnonce, qop, ha2)
+	}
+
+	params := []string{
+		fmt.Sprintf(`username="%s"`, s.username),
+		fmt.Sprintf(`realm="%s"`, realm),
+		fmt.Sprintf(`nonce="%s"`, nonce),
+		fmt.Sprintf(`uri="%s"`, uri),
+		fmt.Sprintf(`algorithm=%s`, algorithm),
+		fmt.Sprintf(`response="%s"`, response),
+	}
+
+	if opaque, ok := s.challenge["opaque"]; ok {
+		params = append(params, fmt.Sprintf(`opaque="%s"`, opaque))
+	}
+
+	if qop != "" {
+		params = append(params, "qop="+qop, "nc="+nc, fmt.Sprintf(`cnonce="%s"`, cnonce))
+	}
+
+	req.Header.Set("Authorization", "Digest "+strings.Join(params, ", "))
+	return nil
+}
+
+// selectQop prefers auth over auth-int, empty if the server doesn't
+// support quality of protection
+func selectQop(value string) string {
+	selected := ""
+	for _, qop := range strings.Split(value, ",") {
+		qop = strings.TrimSpace(qop)
+		if qop == "auth" {
+			return qop
+		}
+		if qop == "auth-int" {
+			selected = qop
+		}
+	}
+	return selected
+}
+
+// parseChallenge parses comma separated `name=value` and `name="value"`
+// parameters
+func parseChallenge(text string) map[string]string {
+	params := make(map[string]string)
+
+	for {
+		text = strings.TrimLeft(text, " \t,")
+		if text == "" {
+			return params
+		}
+
+		i := strings.IndexByte(text, '=')
+		if i < 0 {
+			return params
+		}
+
+		name := strings.ToLower(strings.TrimSpace(text[:i]))
+		text = strings.TrimLeft(text[i+1:], " \t")
+
+		var value string
+		if strings.HasPrefix(text, `"`) {
+			var b strings.Builder
+			i = 1
+			for ; i < len(text) && text[i] != '"'; i++ {
+				if text[i] == '\\' && i+1 < len(text) {
+					i++
+				}
+				b.WriteByte(text[i])
+			}
+			value = b.String()
+			if i < len(text) {
+				i++ // closing quote
+			}
+			text = text[i:]
+		} else {
+			i = strings.IndexByte(text, ',')
+			if i < 0 {
+				i = len(text)
+			}
+			value = strings.TrimSpace(text[:i])
+			text = text[i:]
+		}
+
+		params[name] = value
+	}
+}
+
+func newNonce() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,165 @@
+package http_auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"net/http"
+	"strings"
+
+	"github.com/bluebookrun/bluebook/bcl"
+)
+
+// HMACComponents are parts of the canonical string, headers are added
+// as `header:name`
+var HMACComponents = []string{"method", "path", "host", "date", "body", "body_sha256"}
+
+// DefaultHMACComponents are signed if `components` is not set
+var DefaultHMACComponents = []string{"method", "path", "date", "body_sha256"}
+
+var hmacAlgorithms = map[string]func() hash.Hash{
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+	"sha512": sha512.New,
+}
+
+func parseComponents(expression *bcl.ExpressionNode) ([]string, error) {
+	listNode, err := expression.ValueAsList()
+	if err != nil {
+		return nil, err
+	}
+
+	components := make([]string, 0)
+	for _, node := range listNode.Nodes {
+		stringNode, ok := node.(*bcl.StringNode)
+		if !ok {
+			return nil, fmt.Errorf("list item is not a string: %s", node)
+		}
+
+		component := string(stringNode.Text)
+		if !contains(HMACComponents, component) &&
+			!(strings.HasPrefix(component, "header:") && len(component) > len("header:")) {
+			return nil, fmt.Errorf("unknown hmac component %q, supported components are %s and header:<name>",
+				component, strings.Join(HMACComponents, ", "))
+		}
+		components = append(components, component)
+	}
+
+	if len(components) == 0 {
+		return nil, fmt.Errorf("hmac `components` can't be empty")
+	}
+
+	return components, nil
+}
+
+// algorithm and encoding select the signing code, so they are not
+// interpolated
+func (a *Auth) validateHMAC() error {
+	if algorithm, ok := a.Inputs["algorithm"]; ok {
+		if _, ok := hmacAlgorithms[algorithm]; !ok {
+			return fmt.Errorf("invalid hmac `algorithm` value %q, allowed values are sha1, sha256 and sha512", algorithm)
+		}
+	}
+
+	if encoding, ok := a.Inputs["encoding"]; ok && encoding != "hex" && encoding != "base64" {
+		return fmt.Errorf("invalid hmac `encoding` value %q, allowed values are hex and base64", encoding)
+	}
+
+	return nil
+}
+
+// hmacSigner signs components of the request joined with new lines and
+// sets the signature with a prefix into a header
+type hmacSigner struct {
+	secret     string
+	algorithm  func() hash.Hash
+	encoding   string
+	header     string
+	prefix     string
+	components []string
+}
+
+func newHMACSigner(inputs map[string]string, components []string) *hmacSigner {
+	s := &hmacSigner{
+		secret:     inputs["secret"],
+		algorithm:  sha256.New,
+		encoding:   "hex",
+		header:     "Authorization",
+		prefix:     inputs["prefix"],
+		components: components,
+	}
+
+	if algorithm, ok := hmacAlgorithms[inputs["algorithm"]]; ok {
+		s.algorithm = algorithm
+	}
+
+	if inputs["encoding"] != "" {
+		s.encoding = inputs["encoding"]
+	}
+
+	if inputs["header"] != "" {
+		s.header = inputs["header"]
+	}
+
+	if s.components == nil {
+		s.components = DefaultHMACComponents
+	}
+
+	return s
+}
+
+func (s *hmacSigner) Sign(req *http.Request, body []byte) error {
+	if contains(s.components, "date") && req.Header.Get("Date") == "" {
+		req.Header.Set("Date", now().UTC().Format(http.TimeFormat))
+	}
+
+	mac := hmac.New(s.algorithm, []byte(s.secret))
+	mac.Write([]byte(canonicalString(req, body, s.components)))
+
+	var signature string
+	if s.encoding == "base64" {
+		signature = base64.StdEncoding.EncodeToString(mac.Sum(nil))
+	} else {
+		signature = hex.EncodeToString(mac.Sum(nil))
+	}
+
+	req.Header.Set(s.header, s.prefix+signature)
+	return nil
+}
+
+// canonicalString joins components of the request with new lines
+func canonicalString(req *http.Request, body []byte, components []string) string {
+	parts := make([]string, 0, len(components))
+	for _, component := range components {
+		switch component {
+		case "method":
+			parts = append(parts, req.Method)
+		case "path":
+			parts = append(parts, req.URL.RequestURI())
+		case "host":
+			parts = append(parts, requestHost(req))
+		case "date":
+			parts = append(parts, req.Header.Get("Date"))
+		case "body":
+			parts = append(parts, string(body))
+		case "body_sha256":
+			sum := sha256.Sum256(body)
+			parts = append(parts, hex.EncodeToString(sum[:]))
+		default:
+			parts = append(parts, req.Header.Get(strings.TrimPrefix(component, "header:")))
+		}
+	}
+	return strings.Join(parts, "\n")
+}
+
+func requestHost(req *http.Request) string {
+	if req.Host != "" {
+		return req.Host
+	}
+	return req.URL.Host
+}
@@ -0,0 +1,200 @@
+package http_client
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/cookiejar"
+	"net/url"
+	"sync"
+)
+
+// Cookie modes of http_test
+const (
+	CookiesIsolated = "isolated" // every test execution has its own cookie jar
+	CookiesShared   = "shared"   // all tests share one cookie jar
+	CookiesNone     = "none"     // cookies are not stored
+)
+
+// ValidateCookies checks `cookies` input value.
+func ValidateCookies(mode string) error {
+	switch mode {
+	case CookiesIsolated, CookiesShared, CookiesNone:
+		return nil
+	}
+	return fmt.Errorf("invalid `cookies` value %q, allowed values are %q, %q and %q",
+		mode, CookiesIsolated, CookiesShared, CookiesNone)
+}
+
+// transports are shared by requests with the same connection options
+type transportKey struct {
+	insecureSkipVerify bool
+	caBundle           string
+	clientCert         string
+	clientKey          string
+	proxy              string
+}
+
+// Pool shares transports across the run so that connections are kept alive
+// between requests. Requests with different TLS or proxy options use
+// separate transports.
+type Pool struct {
+	SharedCookieJar http.CookieJar // cookie jar of tests with shared cookies
+
+	mu         sync.Mutex
+	transports map[transportKey]*http.Transport
+}
+
+func NewPool() *Pool {
+	jar, _ := cookiejar.New(nil)
+	return &Pool{
+		SharedCookieJar: jar,
+		transports:      make(map[transportKey]*http.Transport),
+	}
+}
+
+// Client returns HTTP client configured with options. Cookies are stored
+// in jar unless jar is nil.
+func (p *Pool) Client(o *Options, jar http.CookieJar) (*http.Client, error) {
+	if o == nil {
+		o = &Options{}
+	}
+
+	transport, err := p.transport(o)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &http.Client{
+		Transport:     transport,
+		Jar:           jar,
+		Timeout:       DefaultTimeout,
+		CheckRedirect: checkRedirect(o),
+	}
+
+	if o.Timeout != nil {
+		client.Timeout = *o.Timeout
+	}
+
+	return client, nil
+}
+
+func (p *Pool) transport(o *Options) (*http.Transport, error) {
+	key := transportKey{
+		caBundle:   o.CABundle,
+		clientCert: o.ClientCert,
+		clientKey:  o.ClientKey,
+		proxy:      o.Proxy,
+	}
+	if o.InsecureSkipVerify != nil {
+		key.insecureSkipVerify = *o.InsecureSkipVerify
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if transport, ok := p.transports[key]; ok {
+		return transport, nil
+	}
+
+	transport, err := newTransport(o)
+	if err != nil {
+		return nil, err
+	}
+
+	p.transports[key] = transport
+	return transport, nil
+}
+
+// NewCookieJar returns cookie jar for a test execution with cookie mode.
+func (p *Pool) NewCookieJar(mode string) http.CookieJar {
+	switch mode {
+	case CookiesShared:
+		return p.SharedCookieJar
+	case CookiesNone:
+		return nil
+	}
+
+	jar, _ := cookiejar.New(nil)
+	return jar
+}
+
+func checkRedirect(o *Options) func(*http.Request, []*http.Request) error {
+	if o.FollowRedirects != nil && !*o.FollowRedirects {
+		return func(req *http.Request, via []*http.Request) error {
+			// return redirect response as it is
+			return http.ErrUseLastResponse
+		}
+	}
+
+	if o.MaxRedirects != nil {
+		maxRedirects := *o.MaxRedirects
+		return func(req *http.Request, via []*http.Request) error {
+			if len(via) > maxRedirects {
+				return fmt.Errorf("stopped after %d redirects", maxRedirects)
+			}
+			return nil
+		}
+	}
+
+	// default policy, stops after 10 redirects
+	return nil
+}
+
+func newTransport(o *Options) (*http.Transport, error) {
+	tlsConfig, err := newTLSConfig(o)
+	if err != nil {
+		return nil, err
+	}
+
+	proxy := http.ProxyFromEnvironment
+	if o.Proxy != "" {
+		proxyURL, err := url.Parse(o.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL: %s", err.Error())
+		}
+		proxy = http.ProxyURL(proxyURL)
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = proxy
+	transport.TLSClientConfig = tlsConfig
+	return transport, nil
+}
+
+func newTLSConfig(o *Options) (*tls.Config, error) {
+	config := &tls.Config{}
+
+	if o.InsecureSkipVerify != nil {
+		config.InsecureSkipVerify = *o.InsecureSkipVerify
+	}
+
+	if o.CABundle != "" {
+		data, err := ioutil.ReadFile(o.CABundle)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA bundle: %s", err.Error())
+		}
+
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("CA bundle %q does not contain PEM certificates", o.CABundle)
+		}
+		config.RootCAs = pool
+	}
+
+	if o.ClientCert != "" {
+		cert, err := tls.LoadX509KeyPair(o.ClientCert, o.ClientKey)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate: %s", err.Error())
+		}
+		config.Certificates = []tls.Certificate{cert}
+	}
+
+	return config, nil
+}
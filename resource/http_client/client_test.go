@@ -0,0 +1,239 @@
+package http_client
+
+import (
+	"encoding/pem"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bluebookrun/bluebook/bcl"
+)
+
+func parseOptions(t *testing.T, text string) (*Options, error) {
+	tree, err := bcl.Parse(text)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	o := &Options{}
+	for _, expression := range tree.Root.Nodes[0].(*bcl.BlockNode).Expressions {
+		ok, err := o.Parse(expression)
+		if err != nil {
+			return nil, err
+		}
+		assert.True(t, ok)
+	}
+	return o, o.Validate()
+}
+
+func TestParse(t *testing.T) {
+	o, err := parseOptions(t, `settings {
+		timeout = "5s"
+		follow_redirects = "false"
+		max_redirects = "3"
+		insecure_skip_verify = "true"
+		proxy = "http://localhost:3128"
+		max_body_size = "10MB"
+		on_large_body = "fail"
+	}`)
+	assert.Nil(t, err)
+	assert.Equal(t, 5*time.Second, *o.Timeout)
+	assert.False(t, *o.FollowRedirects)
+	assert.Equal(t, 3, *o.MaxRedirects)
+	assert.True(t, *o.InsecureSkipVerify)
+	assert.Equal(t, "http://localhost:3128", o.Proxy)
+	assert.Equal(t, int64(10<<20), *o.MaxBodySize)
+	assert.Equal(t, LargeBodyFail, o.OnLargeBody)
+
+	tests := []string{
+		`settings { timeout = "soon" }`,
+		`settings { follow_redirects = "maybe" }`,
+		`settings { max_redirects = "-1" }`,
+		`settings { insecure_skip_verify = "yes please" }`,
+		`settings { client_cert = "cert.pem" }`,
+		`settings { timeout = ["5s"] }`,
+		`settings { max_body_size = "10 parsecs" }`,
+		`settings { on_large_body = "ignore" }`,
+	}
+
+	for _, test := range tests {
+		_, err := parseOptions(t, test)
+		assert.NotNil(t, err, test)
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	cases := map[string]int64{
+		"0":     0,
+		"512":   512,
+		"512B":  512,
+		"64KB":  64 << 10,
+		"10 MB": 10 << 20,
+		"1gb":   1 << 30,
+	}
+
+	for value, expected := range cases {
+		size, err := ParseSize(value)
+		assert.Nil(t, err, value)
+		assert.Equal(t, expected, size, value)
+	}
+
+	for _, value := range []string{"", "-1", "1.5MB", "MB", "99999999999GB"} {
+		_, err := ParseSize(value)
+		assert.NotNil(t, err, value)
+	}
+}
+
+func TestMerge(t *testing.T) {
+	short := 1 * time.Second
+	long := 10 * time.Second
+	follow := false
+
+	settings := &Options{Timeout: &long, Proxy: "http://proxy"}
+	step := &Options{Timeout: &short, FollowRedirects: &follow}
+
+	merged := settings.Merge(step)
+	assert.Equal(t, short, *merged.Timeout)
+	assert.False(t, *merged.FollowRedirects)
+	assert.Equal(t, "http://proxy", merged.Proxy)
+
+	// merge doesn't modify its inputs
+	assert.Equal(t, long, *settings.Timeout)
+
+	var empty *Options
+	assert.Equal(t, step, empty.Merge(step))
+}
+
+func TestTimeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(200 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	timeout := 10 * time.Millisecond
+	client, err := NewPool().Client(&Options{Timeout: &timeout}, nil)
+	assert.Nil(t, err)
+
+	_, err = client.Get(server.URL)
+	assert.NotNil(t, err)
+}
+
+func TestRedirects(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/final" {
+			return
+		}
+		http.Redirect(w, r, "/final", http.StatusFound)
+	}))
+	defer server.Close()
+
+	client, err := NewPool().Client(nil, nil)
+	assert.Nil(t, err)
+	resp, err := client.Get(server.URL + "/start")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	follow := false
+	client, err = NewPool().Client(&Options{FollowRedirects: &follow}, nil)
+	assert.Nil(t, err)
+	resp, err = client.Get(server.URL + "/start")
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusFound, resp.StatusCode)
+
+	maxRedirects := 0
+	client, err = NewPool().Client(&Options{MaxRedirects: &maxRedirects}, nil)
+	assert.Nil(t, err)
+	_, err = client.Get(server.URL + "/start")
+	assert.NotNil(t, err)
+}
+
+func TestTLS(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	client, err := NewPool().Client(nil, nil)
+	assert.Nil(t, err)
+	_, err = client.Get(server.URL)
+	assert.NotNil(t, err, "self-signed certificate must not be trusted by default")
+
+	insecure := true
+	client, err = NewPool().Client(&Options{InsecureSkipVerify: &insecure}, nil)
+	assert.Nil(t, err)
+	_, err = client.Get(server.URL)
+	assert.Nil(t, err)
+
+	bundle, err := ioutil.TempFile("", "ca-bundle")
+	assert.Nil(t, err)
+	defer os.Remove(bundle.Name())
+
+	pem.Encode(bundle, &pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	bundle.Close()
+
+	client, err = NewPool().Client(&Options{CABundle: bundle.Name()}, nil)
+	assert.Nil(t, err)
+	_, err = client.Get(server.URL)
+	assert.Nil(t, err)
+
+	_, err = NewPool().Client(&Options{CABundle: "/does/not/exist.pem"}, nil)
+	assert.NotNil(t, err)
+}
+
+func TestPoolSharesTransports(t *testing.T) {
+	pool := NewPool()
+	insecure := true
+	timeout := time.Second
+
+	a, err := pool.Client(&Options{}, nil)
+	assert.Nil(t, err)
+	b, err := pool.Client(&Options{Timeout: &timeout}, nil)
+	assert.Nil(t, err)
+	c, err := pool.Client(&Options{InsecureSkipVerify: &insecure}, nil)
+	assert.Nil(t, err)
+
+	assert.True(t, a.Transport == b.Transport)
+	assert.False(t, a.Transport == c.Transport)
+}
+
+func TestCookieJars(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/login" {
+			http.SetCookie(w, &http.Cookie{Name: "session", Value: "secret"})
+			return
+		}
+
+		if _, err := r.Cookie("session"); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	pool := NewPool()
+	status := func(jar http.CookieJar, path string) int {
+		client, err := pool.Client(nil, jar)
+		assert.Nil(t, err)
+		resp, err := client.Get(server.URL + path)
+		assert.Nil(t, err)
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	jar := pool.NewCookieJar(CookiesIsolated)
+	status(jar, "/login")
+	assert.Equal(t, http.StatusOK, status(jar, "/profile"))
+	assert.Equal(t, http.StatusUnauthorized, status(pool.NewCookieJar(CookiesIsolated), "/profile"))
+
+	status(pool.NewCookieJar(CookiesShared), "/login")
+	assert.Equal(t, http.StatusOK, status(pool.NewCookieJar(CookiesShared), "/profile"))
+
+	jar = pool.NewCookieJar(CookiesNone)
+	status(jar, "/login")
+	assert.Equal(t, http.StatusUnauthorized, status(jar, "/profile"))
+
+	assert.Nil(t, ValidateCookies(CookiesShared))
+	assert.NotNil(t, ValidateCookies("sometimes"))
+}
@@ -0,0 +1,199 @@
+package http_client
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bluebookrun/bluebook/bcl"
+)
+
+// DefaultTimeout limits requests that don't configure `timeout`,
+// so that a hung endpoint doesn't hang the whole run.
+const DefaultTimeout = 60 * time.Second
+
+// Options configure HTTP client for a request. Options can be set in the
+// settings block, on http_test and on http_step. Unset fields are inherited
+// from the less specific level.
+type Options struct {
+	Timeout            *time.Duration
+	FollowRedirects    *bool
+	MaxRedirects       *int
+	InsecureSkipVerify *bool
+	CABundle           string // path to PEM encoded CA certificates
+	ClientCert         string // path to PEM encoded client certificate
+	ClientKey          string // path to PEM encoded client key
+	Proxy              string // proxy URL
+	MaxBodySize        *int64 // maximum number of response body bytes kept for assertions
+	OnLargeBody        string // what to do with larger bodies, truncate or fail
+}
+
+// Large body policies
+const (
+	LargeBodyTruncate = "truncate" // keep the beginning of the body
+	LargeBodyFail     = "fail"     // fail the step
+)
+
+// Parse sets option from an expression. It returns false if expression
+// is not a client option.
+func (o *Options) Parse(expression *bcl.ExpressionNode) (bool, error) {
+	field := string(expression.Field.Text)
+
+	switch field {
+	case "timeout",
+		"follow_redirects",
+		"max_redirects",
+		"insecure_skip_verify",
+		"ca_bundle",
+		"client_cert",
+		"client_key",
+		"proxy",
+		"max_body_size",
+		"on_large_body":
+	default:
+		return false, nil
+	}
+
+	value, err := expression.ValueAsString()
+	if err != nil {
+		return true, err
+	}
+
+	switch field {
+	case "timeout":
+		d, err := time.ParseDuration(value)
+		if err != nil || d < 0 {
+			return true, fmt.Errorf("invalid `timeout` value %q", value)
+		}
+		o.Timeout = &d
+	case "follow_redirects":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return true, fmt.Errorf("invalid `follow_redirects` value %q", value)
+		}
+		o.FollowRedirects = &b
+	case "max_redirects":
+		i, err := strconv.Atoi(value)
+		if err != nil || i < 0 {
+			return true, fmt.Errorf("invalid `max_redirects` value %q", value)
+		}
+		o.MaxRedirects = &i
+	case "insecure_skip_verify":
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return true, fmt.Errorf("invalid `insecure_skip_verify` value %q", value)
+		}
+		o.InsecureSkipVerify = &b
+	case "ca_bundle":
+		o.CABundle = value
+	case "client_cert":
+		o.ClientCert = value
+	case "client_key":
+		o.ClientKey = value
+	case "proxy":
+		if _, err := url.Parse(value); err != nil {
+			return true, fmt.Errorf("invalid `proxy` value %q", value)
+		}
+		o.Proxy = value
+	case "max_body_size":
+		size, err := ParseSize(value)
+		if err != nil {
+			return true, fmt.Errorf("invalid `max_body_size` value %q", value)
+		}
+		o.MaxBodySize = &size
+	case "on_large_body":
+		if value != LargeBodyTruncate && value != LargeBodyFail {
+			return true, fmt.Errorf("invalid `on_large_body` value %q, allowed values are %q and %q",
+				value, LargeBodyTruncate, LargeBodyFail)
+		}
+		o.OnLargeBody = value
+	}
+
+	return true, nil
+}
+
+// Validate checks options that depend on each other.
+func (o *Options) Validate() error {
+	if (o.ClientCert == "") != (o.ClientKey == "") {
+		return fmt.Errorf("`client_cert` and `client_key` must be used together")
+	}
+	return nil
+}
+
+// Merge returns a copy of options with fields overridden by set
+// fields of override.
+func (o *Options) Merge(override *Options) *Options {
+	merged := &Options{}
+	if o != nil {
+		*merged = *o
+	}
+
+	if override == nil {
+		return merged
+	}
+
+	if override.Timeout != nil {
+		merged.Timeout = override.Timeout
+	}
+	if override.FollowRedirects != nil {
+		merged.FollowRedirects = override.FollowRedirects
+	}
+	if override.MaxRedirects != nil {
+		merged.MaxRedirects = override.MaxRedirects
+	}
+	if override.InsecureSkipVerify != nil {
+		merged.InsecureSkipVerify = override.InsecureSkipVerify
+	}
+	if override.CABundle != "" {
+		merged.CABundle = override.CABundle
+	}
+	if override.ClientCert != "" {
+		merged.ClientCert = override.ClientCert
+		merged.ClientKey = override.ClientKey
+	}
+	if override.Proxy != "" {
+		merged.Proxy = override.Proxy
+	}
+	if override.MaxBodySize != nil {
+		merged.MaxBodySize = override.MaxBodySize
+	}
+	if override.OnLargeBody != "" {
+		merged.OnLargeBody = override.OnLargeBody
+	}
+
+	return merged
+}
+
+// size units, multiples of 1024
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{"KB", 1 << 10},
+	{"MB", 1 << 20},
+	{"GB", 1 << 30},
+	{"B", 1},
+}
+
+// ParseSize parses number of bytes, e.g. 512, 64KB or 10MB
+func ParseSize(value string) (int64, error) {
+	multiplier := int64(1)
+	number := strings.TrimSpace(value)
+
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(strings.ToUpper(number), unit.suffix) {
+			multiplier = unit.multiplier
+			number = strings.TrimSpace(number[:len(number)-len(unit.suffix)])
+			break
+		}
+	}
+
+	size, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || size < 0 || size > math.MaxInt64/multiplier {
+		return 0, fmt.Errorf("invalid size %q", value)
+	}
+	return size * multiplier, nil
+}
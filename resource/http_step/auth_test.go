@@ -0,0 +1,91 @@
+package http_step
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bluebookrun/bluebook/bcl/bcltest"
+	"github.com/bluebookrun/bluebook/resource"
+	"github.com/bluebookrun/bluebook/resource/http_auth"
+)
+
+func TestAuth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if username, password, ok := r.BasicAuth(); ok {
+			w.Write([]byte("basic " + username + ":" + password))
+			return
+		}
+		w.Write([]byte(r.Header.Get("Authorization")))
+	}))
+	defer server.Close()
+
+	testAuth, err := http_auth.Parse(bcltest.Block(t, `auth "bearer" { token = "${var.token}" }`))
+	assert.Nil(t, err)
+
+	cases := []struct {
+		auth     string
+		expected string
+	}{
+		{``, "Bearer test-token"},
+		{`auth "basic" { username = "user", password = "${var.token}" }`, "basic user:test-token"},
+		{`auth "none" { }`, ""},
+	}
+
+	for _, c := range cases {
+		step, err := New(bcltest.Block(t, `resource "http_step" "auth" {
+			method = "GET"
+			url = "`+server.URL+`"
+			`+c.auth+`
+		}`))
+		assert.Nil(t, err)
+
+		ctx := resource.NewExecutionContext()
+		ctx.SetVariable("token", "test-token")
+		ctx.Auth = testAuth
+		assert.Nil(t, step.Exec(ctx))
+		assert.Equal(t, c.expected, string(ctx.CurrentResponseBody), c.auth)
+	}
+
+	_, err = New(bcltest.Block(t, `resource "http_step" "auth" {
+		method = "GET"
+		url = "/"
+		auth "none" { }
+		auth "none" { }
+	}`))
+	assert.NotNil(t, err)
+}
+
+func TestDigestChallenge(t *testing.T) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if r.Header.Get("Authorization") == "" {
+			w.Header().Set("WWW-Authenticate", `Digest realm="test", nonce="abc", qop="auth"`)
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	step, err := New(bcltest.Block(t, `resource "http_step" "digest" {
+		method = "POST"
+		url = "`+server.URL+`"
+		body = "data"
+		auth "digest" {
+			username = "user"
+			password = "secret"
+		}
+	}`))
+	assert.Nil(t, err)
+
+	ctx := resource.NewExecutionContext()
+	assert.Nil(t, step.Exec(ctx))
+	assert.Equal(t, 200, ctx.CurrentResponse.StatusCode)
+	assert.Equal(t, 2, requests)
+
+	// the challenge is answered within one attempt
+	assert.Equal(t, 1, len(ctx.CurrentResult.Attempts))
+	assert.Equal(t, 200, ctx.CurrentResult.Attempts[0].StatusCode)
+}
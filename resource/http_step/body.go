@@ -0,0 +1,173 @@
+package http_step
+
+import (
+	"bufio"
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/andybalholm/brotli"
+
+	"github.com/bluebookrun/bluebook/resource"
+	"github.com/bluebookrun/bluebook/resource/http_client"
+)
+
+var errBodyTooLarge = errors.New("response body is too large")
+
+// limitedBuffer keeps up to limit bytes, negative limit keeps everything
+type limitedBuffer struct {
+	bytes.Buffer
+	limit     int64
+	fail      bool // return an error instead of discarding bytes over the limit
+	truncated bool
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if b.limit < 0 {
+		return b.Buffer.Write(p)
+	}
+
+	free := b.limit - int64(b.Len())
+	if int64(len(p)) <= free {
+		return b.Buffer.Write(p)
+	}
+
+	if b.fail {
+		return 0, errBodyTooLarge
+	}
+
+	b.truncated = true
+	b.Buffer.Write(p[:free])
+	// report the whole write, so that hashes get the rest of the body
+	return len(p), nil
+}
+
+// readBody reads decoded response body into the context. Size and
+// hashes are computed over the whole body, even if only the beginning
+// of the body is kept.
+func readBody(ctx *resource.ExecutionContext, resp *http.Response, options *http_client.Options) error {
+	encoding := contentEncoding(resp)
+
+	var reader io.Reader = resp.Body
+	if !resp.Uncompressed && isSupportedEncoding(encoding) && !isEmptyBody(&reader) {
+		var err error
+		reader, err = decodeBody(reader, encoding)
+		if err != nil {
+			return fmt.Errorf("unable to decode %s response body: %s", encoding, err.Error())
+		}
+
+		// the same as the transport does for gzip bodies
+		resp.Header.Del("Content-Encoding")
+		resp.Header.Del("Content-Length")
+		resp.ContentLength = -1
+		resp.Uncompressed = true
+	}
+
+	body := &limitedBuffer{
+		limit: -1,
+		fail:  options.OnLargeBody == http_client.LargeBodyFail,
+	}
+	if options.MaxBodySize != nil {
+		body.limit = *options.MaxBodySize
+	}
+
+	hashes := map[string]hash.Hash{
+		"md5":    md5.New(),
+		"sha1":   sha1.New(),
+		"sha256": sha256.New(),
+	}
+
+	writers := []io.Writer{body}
+	for _, h := range hashes {
+		writers = append(writers, h)
+	}
+
+	size, err := io.Copy(io.MultiWriter(writers...), reader)
+	if err == errBodyTooLarge {
+		return fmt.Errorf("response body is larger than max_body_size of %d bytes", body.limit)
+	}
+	if err != nil {
+		return err
+	}
+
+	ctx.CurrentResponse = resp
+	ctx.CurrentResponseBody = body.Bytes()
+
+	result := ctx.CurrentResult
+	result.ContentEncoding = encoding
+	result.BodySize = size
+	result.BodyTruncated = body.truncated
+	result.BodyHashes = make(map[string]string)
+	for name, h := range hashes {
+		result.BodyHashes[name] = hex.EncodeToString(h.Sum(nil))
+	}
+
+	if body.truncated {
+		fmt.Printf("    response body of %d bytes truncated to %d bytes\n", size, body.limit)
+	}
+
+	return nil
+}
+
+// contentEncoding returns original encoding of the response body
+func contentEncoding(resp *http.Response) string {
+	if resp.Uncompressed {
+		// decompressed by the transport
+		return "gzip"
+	}
+
+	encoding := strings.ToLower(strings.TrimSpace(resp.Header.Get("Content-Encoding")))
+	if encoding == "identity" {
+		return ""
+	}
+	return encoding
+}
+
+// isEmptyBody peeks at the body, e.g. of HEAD requests and 204
+// responses, which have no encoded data even if Content-Encoding is set.
+// reader is replaced with a reader that still returns the peeked byte.
+func isEmptyBody(reader *io.Reader) bool {
+	buffered := bufio.NewReader(*reader)
+	*reader = buffered
+	_, err := buffered.Peek(1)
+	return err == io.EOF
+}
+
+// unsupported encodings are kept as they are
+func isSupportedEncoding(encoding string) bool {
+	switch encoding {
+	case "gzip", "x-gzip", "br", "deflate":
+		return true
+	}
+	return false
+}
+
+func decodeBody(body io.Reader, encoding string) (io.Reader, error) {
+	switch encoding {
+	case "gzip", "x-gzip":
+		return gzip.NewReader(body)
+	case "br":
+		return brotli.NewReader(body), nil
+	case "deflate":
+		// deflate should be zlib wrapped, but some servers send raw deflate
+		buffered := bufio.NewReader(body)
+		header, err := buffered.Peek(2)
+		if err == nil && header[0]&0x0f == 8 && (uint16(header[0])<<8|uint16(header[1]))%31 == 0 {
+			return zlib.NewReader(buffered)
+		}
+		return flate.NewReader(buffered), nil
+	}
+
+	return nil, fmt.Errorf("unsupported encoding")
+}
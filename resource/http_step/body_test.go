@@ -0,0 +1,144 @@
+package http_step
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/gzip"
+	"compress/zlib"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bluebookrun/bluebook/bcl/bcltest"
+	"github.com/bluebookrun/bluebook/resource"
+)
+
+func encode(encoding string, data string) []byte {
+	b := &bytes.Buffer{}
+
+	var w io.WriteCloser
+	switch encoding {
+	case "gzip":
+		w = gzip.NewWriter(b)
+	case "br":
+		w = brotli.NewWriter(b)
+	case "deflate":
+		w = zlib.NewWriter(b)
+	case "raw-deflate":
+		w, _ = flate.NewWriter(b, flate.DefaultCompression)
+	default:
+		b.WriteString(data)
+		return b.Bytes()
+	}
+
+	w.Write([]byte(data))
+	w.Close()
+	return b.Bytes()
+}
+
+func TestBodyDecoding(t *testing.T) {
+	body := strings.Repeat("hello ", 100)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encoding := r.URL.Query().Get("encoding")
+		if encoding != "" {
+			w.Header().Set("Content-Encoding", strings.TrimPrefix(encoding, "raw-"))
+		}
+		w.Write(encode(encoding, body))
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		encoding string
+		expected string
+	}{
+		{"", ""},
+		{"gzip", "gzip"},
+		{"br", "br"},
+		{"deflate", "deflate"},
+		{"raw-deflate", "deflate"},
+	}
+
+	for _, c := range cases {
+		step, err := New(bcltest.Block(t, `resource "http_step" "encoding" {
+			method = "GET"
+			url = "`+server.URL+`"
+			query = { encoding = "`+c.encoding+`" }
+		}`))
+		assert.Nil(t, err)
+
+		ctx := resource.NewExecutionContext()
+		assert.Nil(t, step.Exec(ctx), c.encoding)
+		assert.Equal(t, body, string(ctx.CurrentResponseBody), c.encoding)
+		assert.Equal(t, c.expected, ctx.CurrentResult.ContentEncoding, c.encoding)
+		assert.Equal(t, "", ctx.CurrentResponse.Header.Get("Content-Encoding"), c.encoding)
+		assert.Equal(t, int64(len(body)), ctx.CurrentResult.BodySize, c.encoding)
+	}
+}
+
+func TestEmptyEncodedBody(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Encoding", r.URL.Query().Get("encoding"))
+		if r.Method == "HEAD" {
+			w.Header().Set("Content-Length", "20")
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	for _, method := range []string{"HEAD", "DELETE"} {
+		for _, encoding := range []string{"gzip", "br", "deflate"} {
+			step, err := New(bcltest.Block(t, `resource "http_step" "empty" {
+				method = "`+method+`"
+				url = "`+server.URL+`"
+				query = { encoding = "`+encoding+`" }
+			}`))
+			assert.Nil(t, err)
+
+			ctx := resource.NewExecutionContext()
+			assert.Nil(t, step.Exec(ctx), method+" "+encoding)
+			assert.Equal(t, "", string(ctx.CurrentResponseBody), method+" "+encoding)
+			assert.Equal(t, encoding, ctx.CurrentResult.ContentEncoding, method+" "+encoding)
+			assert.Equal(t, int64(0), ctx.CurrentResult.BodySize, method+" "+encoding)
+		}
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "hello world")
+	}))
+	defer server.Close()
+
+	step, err := New(bcltest.Block(t, `resource "http_step" "large" {
+		method = "GET"
+		url = "`+server.URL+`"
+		max_body_size = 5
+	}`))
+	assert.Nil(t, err)
+
+	ctx := resource.NewExecutionContext()
+	assert.Nil(t, step.Exec(ctx))
+	assert.Equal(t, "hello", string(ctx.CurrentResponseBody))
+	assert.True(t, ctx.CurrentResult.BodyTruncated)
+	assert.Equal(t, int64(11), ctx.CurrentResult.BodySize)
+	assert.Equal(t, "b94d27b9934d3e08a52e52d7da7dabfac484efe37a5380ee9088f7ace2efcde9", ctx.CurrentResult.BodyHashes["sha256"])
+	assert.Equal(t, "5eb63bbbe01eeed093cb22bb8f5acdc3", ctx.CurrentResult.BodyHashes["md5"])
+
+	step.ClientOptions.OnLargeBody = "fail"
+	err = step.Exec(ctx)
+	assert.NotNil(t, err)
+	assert.Equal(t, "response body is larger than max_body_size of 5 bytes", err.Error())
+
+	size := int64(11)
+	step.ClientOptions.MaxBodySize = &size
+	assert.Nil(t, step.Exec(ctx))
+	assert.Equal(t, "hello world", string(ctx.CurrentResponseBody))
+	assert.False(t, ctx.CurrentResult.BodyTruncated)
+}
@@ -0,0 +1,309 @@
+package http_step
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/textproto"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bluebookrun/bluebook/bcl"
+	"github.com/bluebookrun/bluebook/interpolator"
+	"github.com/bluebookrun/bluebook/resource"
+)
+
+// FormField is a form field or a query parameter with one or more values
+type FormField struct {
+	Name   string
+	Values []string
+}
+
+// Multipart describes multipart/form-data request body
+type Multipart struct {
+	Fields []FormField
+	Files  []MultipartFile
+}
+
+// MultipartFile is a file uploaded in a multipart body
+type MultipartFile struct {
+	Field       string // form field name
+	Path        string // path to the file
+	ContentType string // detected from file extension if empty
+	FileName    string // base name of the path if empty
+
+	Node *bcl.BlockNode // file block, relative path is resolved against its file
+}
+
+// parseFormField parses `name = "value"` or `name = ["a", "b"]`
+func parseFormField(expression *bcl.ExpressionNode) (FormField, error) {
+	field := FormField{
+		Name:   string(expression.Field.Text),
+		Values: make([]string, 0),
+	}
+
+	switch value := expression.Value.(type) {
+	case *bcl.StringNode:
+		field.Values = append(field.Values, string(value.Text))
+	case *bcl.ListNode:
+		for _, node := range value.Nodes {
+			stringNode, ok := node.(*bcl.StringNode)
+			if !ok {
+				return field, fmt.Errorf("form field %q list item is not a string: %s", field.Name, node)
+			}
+			field.Values = append(field.Values, string(stringNode.Text))
+		}
+	default:
+		return field, fmt.Errorf("form field %q must be a string or a list of strings", field.Name)
+	}
+
+	return field, nil
+}
+
+func parseForm(mapNode *bcl.MapNode) ([]FormField, error) {
+	fields := make([]FormField, 0)
+	for _, entry := range mapNode.Entries {
+		field, err := parseFormField(entry)
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, field)
+	}
+	return fields, nil
+}
+
+func parseMultipart(block *bcl.BlockNode) (*Multipart, error) {
+	m := &Multipart{
+		Fields: make([]FormField, 0),
+		Files:  make([]MultipartFile, 0),
+	}
+
+	for _, expression := range block.Expressions {
+		field, err := parseFormField(expression)
+		if err != nil {
+			return nil, err
+		}
+		m.Fields = append(m.Fields, field)
+	}
+
+	for _, fileBlock := range block.Blocks {
+		if string(fileBlock.Id.Text) != "file" {
+			return nil, fmt.Errorf("unknown block %q in multipart block", fileBlock.Id.Text)
+		}
+
+		if len(fileBlock.Driver.Text) != 0 || len(fileBlock.Name.Text) == 0 {
+			return nil, fmt.Errorf("file block requires a field name, e.g. file \"avatar\" { ... }")
+		}
+
+		file, err := parseMultipartFile(fileBlock)
+		if err != nil {
+			return nil, err
+		}
+		m.Files = append(m.Files, file)
+	}
+
+	return m, nil
+}
+
+func parseMultipartFile(block *bcl.BlockNode) (MultipartFile, error) {
+	file := MultipartFile{
+		Field: string(block.Name.Text),
+		Node:  block,
+	}
+
+	if len(block.Blocks) != 0 {
+		return file, fmt.Errorf("unknown block %q in file block", block.Blocks[0].Id.Text)
+	}
+
+	for _, expression := range block.Expressions {
+		value, err := expression.ValueAsString()
+		if err != nil {
+			return file, err
+		}
+
+		switch {
+		case string(expression.Field.Text) == "path":
+			file.Path = value
+		case string(expression.Field.Text) == "content_type":
+			file.ContentType = value
+		case string(expression.Field.Text) == "filename":
+			file.FileName = value
+		default:
+			return file, fmt.Errorf("unknown file input %q", expression.Field.Text)
+		}
+	}
+
+	if file.Path == "" {
+		return file, fmt.Errorf("file %q: `path` is required", file.Field)
+	}
+
+	return file, nil
+}
+
+// parseQuery parses a map, or a list of name and value pairs that
+// keeps parameter order, e.g. [["id", "1"], ["id", "2"]]
+func parseQuery(expression *bcl.ExpressionNode) ([]FormField, error) {
+	if mapNode, ok := expression.Value.(*bcl.MapNode); ok {
+		return parseForm(mapNode)
+	}
+
+	listNode, err := expression.ValueAsList()
+	if err != nil {
+		return nil, fmt.Errorf("`query` must be a map or a list of pairs")
+	}
+
+	fields := make([]FormField, 0)
+	for _, node := range listNode.Nodes {
+		pair, ok := node.(*bcl.ListNode)
+		if !ok || len(pair.Nodes) != 2 {
+			return nil, fmt.Errorf("`query` list items must be name and value pairs: %s", node)
+		}
+
+		name, ok := pair.Nodes[0].(*bcl.StringNode)
+		if !ok {
+			return nil, fmt.Errorf("`query` parameter name is not a string: %s", pair)
+		}
+
+		value, ok := pair.Nodes[1].(*bcl.StringNode)
+		if !ok {
+			return nil, fmt.Errorf("`query` parameter value is not a string: %s", pair)
+		}
+
+		fields = append(fields, FormField{
+			Name:   string(name.Text),
+			Values: []string{string(value.Text)},
+		})
+	}
+	return fields, nil
+}
+
+// encodeValues interpolates and URL encodes fields, e.g. form or query
+// parameters, in the order they are defined
+func encodeValues(fields []FormField, ctx *resource.ExecutionContext) (string, error) {
+	var b strings.Builder
+	for _, field := range fields {
+		for _, value := range field.Values {
+			v, err := interpolator.Eval(value, ctx)
+			if err != nil {
+				return "", err
+			}
+
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(url.QueryEscape(field.Name))
+			b.WriteByte('=')
+			b.WriteString(url.QueryEscape(v))
+		}
+	}
+	return b.String(), nil
+}
+
+// renderURL adds query parameters to the URL, parameters already in
+// the URL are kept as they are
+func renderURL(rawURL string, fields []FormField, ctx *resource.ExecutionContext) (string, error) {
+	if len(fields) == 0 {
+		return rawURL, nil
+	}
+
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return "", err
+	}
+
+	query, err := encodeValues(fields, ctx)
+	if err != nil {
+		return "", err
+	}
+
+	if u.RawQuery != "" && query != "" {
+		u.RawQuery += "&"
+	}
+	u.RawQuery += query
+	return u.String(), nil
+}
+
+// render returns multipart body and its content type with boundary
+func (m *Multipart) render(ctx *resource.ExecutionContext) (*bytes.Buffer, string, error) {
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+
+	for _, field := range m.Fields {
+		for _, value := range field.Values {
+			v, err := interpolator.Eval(value, ctx)
+			if err != nil {
+				return nil, "", err
+			}
+
+			if err := writer.WriteField(field.Name, v); err != nil {
+				return nil, "", err
+			}
+		}
+	}
+
+	for _, file := range m.Files {
+		if err := file.write(writer, ctx); err != nil {
+			return nil, "", err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, "", err
+	}
+
+	return body, writer.FormDataContentType(), nil
+}
+
+var quoteEscaper = strings.NewReplacer("\\", "\\\\", `"`, "\\\"")
+
+func (f *MultipartFile) write(writer *multipart.Writer, ctx *resource.ExecutionContext) error {
+	path, err := interpolator.Eval(f.Path, ctx)
+	if err != nil {
+		return err
+	}
+
+	contentType, err := interpolator.Eval(f.ContentType, ctx)
+	if err != nil {
+		return err
+	}
+
+	if contentType == "" {
+		contentType = mime.TypeByExtension(filepath.Ext(path))
+	}
+
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	fileName, err := interpolator.Eval(f.FileName, ctx)
+	if err != nil {
+		return err
+	}
+
+	if fileName == "" {
+		fileName = filepath.Base(path)
+	}
+
+	file, err := os.Open(f.Node.Path(path))
+	if err != nil {
+		return fmt.Errorf("unable to open file %q: %s", f.Field, err.Error())
+	}
+	defer file.Close()
+
+	header := make(textproto.MIMEHeader)
+	header.Set("Content-Disposition", fmt.Sprintf(`form-data; name="%s"; filename="%s"`,
+		quoteEscaper.Replace(f.Field), quoteEscaper.Replace(fileName)))
+	header.Set("Content-Type", contentType)
+
+	part, err := writer.CreatePart(header)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.Copy(part, file)
+	return err
+}
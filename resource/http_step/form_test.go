@@ -0,0 +1,172 @@
+package http_step
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bluebookrun/bluebook/bcl/bcltest"
+	"github.com/bluebookrun/bluebook/resource"
+)
+
+func TestFormBody(t *testing.T) {
+	var form map[string][]string
+	var contentType string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		r.ParseForm()
+		form = r.PostForm
+	}))
+	defer server.Close()
+
+	step, err := New(bcltest.Block(t, `resource "http_step" "form" {
+		method = "POST"
+		url = "`+server.URL+`"
+		form = {
+			name = "${var.name}"
+			tags = ["a&b", "c d"]
+		}
+	}`))
+	assert.Nil(t, err)
+
+	ctx := resource.NewExecutionContext()
+	ctx.SetVariable("name", "ünïcode")
+	assert.Nil(t, step.Exec(ctx))
+
+	assert.Equal(t, "application/x-www-form-urlencoded", contentType)
+	assert.Equal(t, map[string][]string{
+		"name": {"ünïcode"},
+		"tags": {"a&b", "c d"},
+	}, form)
+}
+
+func TestMultipartBody(t *testing.T) {
+	dir, err := ioutil.TempDir("", "multipart")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "avatar.png")
+	assert.Nil(t, ioutil.WriteFile(path, []byte("png data"), 0644))
+
+	type upload struct {
+		fileName    string
+		contentType string
+		content     string
+	}
+	var fields map[string][]string
+	var files map[string]upload
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := r.ParseMultipartForm(1 << 20); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+
+		fields = r.MultipartForm.Value
+		files = make(map[string]upload)
+		for name, headers := range r.MultipartForm.File {
+			file, _ := headers[0].Open()
+			data, _ := ioutil.ReadAll(file)
+			files[name] = upload{headers[0].Filename, headers[0].Header.Get("Content-Type"), string(data)}
+		}
+	}))
+	defer server.Close()
+
+	step, err := New(bcltest.Block(t, `resource "http_step" "upload" {
+		method = "POST"
+		url = "`+server.URL+`"
+
+		multipart {
+			title = "profile"
+
+			file "avatar" {
+				path = "`+path+`"
+			}
+
+			file "document" {
+				path = "`+path+`"
+				content_type = "text/plain"
+				filename = "notes.txt"
+			}
+		}
+	}`))
+	assert.Nil(t, err)
+
+	ctx := resource.NewExecutionContext()
+	assert.Nil(t, step.Exec(ctx))
+	assert.Equal(t, 200, ctx.CurrentResponse.StatusCode)
+
+	assert.Equal(t, map[string][]string{"title": {"profile"}}, fields)
+	assert.Equal(t, map[string]upload{
+		"avatar":   {"avatar.png", "image/png", "png data"},
+		"document": {"notes.txt", "text/plain", "png data"},
+	}, files)
+}
+
+func TestBodyValidation(t *testing.T) {
+	invalid := []string{
+		`resource "http_step" "a" { method = "POST", url = "/", body = "a", form = { a = "b" } }`,
+		`resource "http_step" "a" { method = "POST", url = "/", body = "a" multipart { a = "b" } }`,
+		`resource "http_step" "a" { method = "POST", url = "/", multipart { } multipart { } }`,
+		`resource "http_step" "a" { method = "POST", url = "/", multipart { file "a" { } } }`,
+		`resource "http_step" "a" { method = "POST", url = "/", multipart { file { path = "a" } } }`,
+		`resource "http_step" "a" { method = "POST", url = "/", form = { a = { b = "c" } } }`,
+		`resource "http_step" "a" { method = "POST", url = "/", unknown { } }`,
+	}
+
+	for _, text := range invalid {
+		_, err := New(bcltest.Block(t, text))
+		assert.NotNil(t, err, text)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	var rawQuery string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawQuery = r.URL.RawQuery
+	}))
+	defer server.Close()
+
+	cases := []struct {
+		query    string
+		expected string
+	}{
+		{`{ q = "a&b c", tags = ["x", "ü"] }`, "q=a%26b+c&tags=x&tags=%C3%BC"},
+		{`[["id", "2"], ["name", "${var.name}"], ["id", "1"]]`, "id=2&name=bluebook&id=1"},
+	}
+
+	for _, c := range cases {
+		step, err := New(bcltest.Block(t, `resource "http_step" "query" {
+			method = "GET"
+			url = "`+server.URL+`/search?page=1"
+			query = `+c.query+`
+		}`))
+		assert.Nil(t, err)
+
+		ctx := resource.NewExecutionContext()
+		ctx.SetVariable("name", "bluebook")
+		assert.Nil(t, step.Exec(ctx))
+
+		assert.Equal(t, "page=1&"+c.expected, rawQuery)
+		assert.Equal(t, server.URL+"/search?page=1&"+c.expected, ctx.CurrentResult.URL)
+	}
+
+	invalid := []string{
+		`"a=b"`,
+		`[["a"]]`,
+		`["a", "b"]`,
+		`[[["a"], "b"]]`,
+	}
+
+	for _, query := range invalid {
+		_, err := New(bcltest.Block(t, `resource "http_step" "query" { method = "GET", url = "/", query = `+query+` }`))
+		assert.NotNil(t, err, query)
+	}
+}
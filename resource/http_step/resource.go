@@ -1,15 +1,19 @@
 package http_step
 
 import (
+	"bytes"
 	"fmt"
 	"github.com/bluebookrun/bluebook/bcl"
 	"github.com/bluebookrun/bluebook/evaluator/proxy"
 	"github.com/bluebookrun/bluebook/interpolator"
 	"github.com/bluebookrun/bluebook/resource"
+	"github.com/bluebookrun/bluebook/resource/http_auth"
+	"github.com/bluebookrun/bluebook/resource/http_client"
 	"github.com/google/uuid"
+	"io"
 	"io/ioutil"
 	"net/http"
-	"strings"
+	"time"
 )
 
 type Resource struct {
@@ -20,6 +24,15 @@ type Resource struct {
 	Method     string
 	Url        string
 	Body       string
+	BodyFile   string // path to a text/template file rendered into the body
+	Form       []FormField
+	Query      []FormField // query parameters added to the URL
+	Retry      *Retry      // nil if failed requests are not retried
+	WaitUntil  *WaitUntil  // nil if the request is not polled
+	Multipart  *Multipart
+	Auth       *http_auth.Auth // replaces authentication of the test, nil to inherit it
+
+	ClientOptions *http_client.Options
 
 	attributes map[string]string
 }
@@ -29,6 +42,8 @@ func New(node *bcl.BlockNode) (*Resource, error) {
 		Node:       node,
 		Assertions: make([]*proxy.Proxy, 0),
 		Headers:    make([]string, 0),
+
+		ClientOptions: &http_client.Options{},
 		attributes: map[string]string{
 			"id": uuid.New().String(),
 		},
@@ -101,6 +116,78 @@ func New(node *bcl.BlockNode) (*Resource, error) {
 				return nil, err
 			}
 			d.Body = value
+		case string(expression.Field.Text) == "body_file":
+			value, err := expression.ValueAsString()
+			if err != nil {
+				return nil, err
+			}
+			d.BodyFile = value
+		case string(expression.Field.Text) == "query":
+			query, err := parseQuery(expression)
+			if err != nil {
+				return nil, err
+			}
+			d.Query = query
+		case string(expression.Field.Text) == "form":
+			mapNode, err := expression.ValueAsMap()
+			if err != nil {
+				return nil, err
+			}
+			d.Form, err = parseForm(mapNode)
+			if err != nil {
+				return nil, err
+			}
+		default:
+			if _, err := d.ClientOptions.Parse(expression); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	for _, block := range node.Blocks {
+		switch {
+		case string(block.Id.Text) == "multipart":
+			if d.Multipart != nil {
+				return nil, fmt.Errorf("only one `multipart` block is allowed")
+			}
+
+			multipart, err := parseMultipart(block)
+			if err != nil {
+				return nil, err
+			}
+			d.Multipart = multipart
+		case string(block.Id.Text) == "retry":
+			if d.Retry != nil {
+				return nil, fmt.Errorf("only one `retry` block is allowed")
+			}
+
+			retry, err := parseRetry(block)
+			if err != nil {
+				return nil, err
+			}
+			d.Retry = retry
+		case string(block.Id.Text) == "wait_until":
+			if d.WaitUntil != nil {
+				return nil, fmt.Errorf("only one `wait_until` block is allowed")
+			}
+
+			waitUntil, err := parseWaitUntil(block)
+			if err != nil {
+				return nil, err
+			}
+			d.WaitUntil = waitUntil
+		case string(block.Id.Text) == "auth":
+			if d.Auth != nil {
+				return nil, fmt.Errorf("only one `auth` block is allowed")
+			}
+
+			auth, err := http_auth.Parse(block)
+			if err != nil {
+				return nil, err
+			}
+			d.Auth = auth
+		default:
+			return nil, fmt.Errorf("unknown block %q", block.Id.Text)
 		}
 	}
 
@@ -112,6 +199,21 @@ func New(node *bcl.BlockNode) (*Resource, error) {
 		return nil, fmt.Errorf("`url` is required")
 	}
 
+	bodies := 0
+	for _, set := range []bool{d.Body != "", d.BodyFile != "", d.Form != nil, d.Multipart != nil} {
+		if set {
+			bodies++
+		}
+	}
+
+	if bodies > 1 {
+		return nil, fmt.Errorf("only one of `body`, `body_file`, `form` and `multipart` can be used")
+	}
+
+	if err := d.ClientOptions.Validate(); err != nil {
+		return nil, err
+	}
+
 	return d, nil
 
 }
@@ -144,6 +246,8 @@ func (r *Resource) Exec(ctx *resource.ExecutionContext) error {
 	// always previous response
 	ctx.CurrentResponse = nil
 	ctx.CurrentResponseBody = []byte{}
+	ctx.CurrentResult = nil
+	ctx.CurrentStep = r.Node.Ref()
 
 	// process variables before the request. This way we can capture
 	// system variables
@@ -159,66 +263,224 @@ func (r *Resource) Exec(ctx *resource.ExecutionContext) error {
 		return err
 	}
 
+	url, err = renderURL(url, r.Query, ctx)
+	if err != nil {
+		return err
+	}
+
 	method, err := interpolator.Eval(r.Method, ctx)
 	if err != nil {
 		return err
 	}
 
-	body, err := interpolator.Eval(r.Body, ctx)
+	options := ctx.ClientOptions.Merge(r.ClientOptions)
+	client, err := ctx.Clients.Client(options, ctx.CookieJar)
 	if err != nil {
 		return err
 	}
 
-	bodyReader := strings.NewReader(body)
+	auth := r.Auth
+	if auth == nil {
+		auth = ctx.Auth
+	}
 
-	// get client via factory from state
-	req, err := http.NewRequest(
-		method, url, bodyReader)
+	signer, err := auth.Signer(func(text string) (string, error) {
+		return interpolator.Eval(text, ctx)
+	})
 	if err != nil {
 		return err
 	}
 
-	for i := 0; i < len(r.Headers); i += 2 {
-		name, err := interpolator.Eval(r.Headers[i], ctx)
-		if err != nil {
+	ctx.CurrentResult = &resource.StepResult{
+		Method:   method,
+		URL:      url,
+		Attempts: make([]resource.Attempt, 0),
+	}
+
+	if r.WaitUntil != nil {
+		if err := r.poll(ctx, client, signer, options, method, url); err != nil {
+			return err
+		}
+	} else {
+		if err := r.request(ctx, client, signer, options, method, url); err != nil {
 			return err
 		}
 
-		value, err := interpolator.Eval(r.Headers[i+1], ctx)
+		for _, proxy := range r.Assertions {
+			err = proxy.Resource.Exec(ctx)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// capture state for next step
+	for _, proxy := range r.Variables {
+		err = proxy.Resource.Exec(ctx)
 		if err != nil {
 			return err
 		}
-		req.Header.Set(name, value)
 	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	return err
+}
+
+// request sends the request and reads the response into the context
+func (r *Resource) request(ctx *resource.ExecutionContext, client *http.Client, signer http_auth.Signer, options *http_client.Options, method string, url string) error {
+	resp, t, err := r.send(ctx, client, signer, method, url)
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
-	// todo don't read large bodies
-	ctx.CurrentResponse = resp
-	ctx.CurrentResponseBody, err = ioutil.ReadAll(resp.Body)
-	if err != nil {
+	if err := readBody(ctx, resp, options); err != nil {
 		return err
 	}
 
-	for _, proxy := range r.Assertions {
-		err = proxy.Resource.Exec(ctx)
+	ctx.CurrentResult.Timings = t.finish()
+	return nil
+}
+
+// send makes the request, and repeats it according to the retry policy.
+// Every attempt is recorded in the step result. A request answered with
+// an authentication challenge is sent again within the same attempt.
+// Returned tracer records timings of the request that got the response.
+func (r *Resource) send(ctx *resource.ExecutionContext, client *http.Client, signer http_auth.Signer, method string, url string) (*http.Response, *tracer, error) {
+	attempts := 1
+	if r.Retry != nil {
+		attempts = r.Retry.Attempts
+	}
+
+	for attempt := 1; ; attempt++ {
+		req, err := r.newRequest(ctx, signer, method, url)
 		if err != nil {
-			return err
+			return nil, nil, err
+		}
+
+		start := time.Now()
+		t := &tracer{}
+		resp, err := client.Do(t.trace(req))
+		if challenger, ok := signer.(http_auth.Challenger); ok && err == nil && challenger.Challenge(resp) {
+			discard(resp)
+
+			req, err = r.newRequest(ctx, signer, method, url)
+			if err != nil {
+				return nil, nil, err
+			}
+			t = &tracer{}
+			resp, err = client.Do(t.trace(req))
+		}
+
+		record := resource.Attempt{Duration: time.Since(start)}
+		if err != nil {
+			record.Error = err.Error()
+		} else {
+			record.StatusCode = resp.StatusCode
 		}
+		ctx.CurrentResult.Attempts = append(ctx.CurrentResult.Attempts, record)
+
+		if attempt >= attempts || !r.Retry.retryable(resp, err) {
+			if err != nil && attempt > 1 {
+				return nil, nil, fmt.Errorf("%s (%d attempts)", err.Error(), attempt)
+			}
+			return resp, t, err
+		}
+
+		if resp != nil {
+			discard(resp)
+		}
+
+		delay := r.Retry.delay(attempt)
+		fmt.Printf("    attempt %d/%d failed: %s, retrying in %s\n", attempt, attempts, record, delay)
+		sleep(delay)
 	}
+}
 
-	// capture state for next step
-	for _, proxy := range r.Variables {
-		err = proxy.Resource.Exec(ctx)
+// discard drains the body so the connection can be reused
+func discard(resp *http.Response) {
+	io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+}
+
+// newRequest creates signed request with body and headers, body is
+// created for every attempt
+func (r *Resource) newRequest(ctx *resource.ExecutionContext, signer http_auth.Signer, method string, url string) (*http.Request, error) {
+	body, contentType, err := r.newBody(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(method, url, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+
+	// headers can override the content type
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	for i := 0; i < len(r.Headers); i += 2 {
+		name, err := interpolator.Eval(r.Headers[i], ctx)
 		if err != nil {
-			return err
+			return nil, err
+		}
+
+		value, err := interpolator.Eval(r.Headers[i+1], ctx)
+		if err != nil {
+			return nil, err
 		}
+		req.Header.Set(name, value)
 	}
 
-	return err
+	if signer != nil {
+		if err := signer.Sign(req, body); err != nil {
+			return nil, fmt.Errorf("unable to sign request: %s", err.Error())
+		}
+	}
+
+	return req, nil
+}
+
+// newBody returns request body and its content type, content type is
+// empty for bodies set with `body` and `body_file`
+func (r *Resource) newBody(ctx *resource.ExecutionContext) ([]byte, string, error) {
+	switch {
+	case r.Form != nil:
+		body, err := encodeValues(r.Form, ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		return []byte(body), "application/x-www-form-urlencoded", nil
+	case r.Multipart != nil:
+		body, contentType, err := r.Multipart.render(ctx)
+		if err != nil {
+			return nil, "", err
+		}
+		return body.Bytes(), contentType, nil
+	}
+
+	body, err := r.renderBody(ctx)
+	if err != nil {
+		return nil, "", err
+	}
+	return []byte(body), "", nil
+}
+
+func (r *Resource) renderBody(ctx *resource.ExecutionContext) (string, error) {
+	if r.BodyFile == "" {
+		return interpolator.Eval(r.Body, ctx)
+	}
+
+	fileName, err := interpolator.Eval(r.BodyFile, ctx)
+	if err != nil {
+		return "", err
+	}
+
+	data, err := ioutil.ReadFile(r.Node.Path(fileName))
+	if err != nil {
+		return "", err
+	}
+
+	return interpolator.Render(fileName, string(data), ctx)
 }
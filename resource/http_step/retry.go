@@ -0,0 +1,151 @@
+package http_step
+
+import (
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bluebookrun/bluebook/bcl"
+)
+
+// backoff strategies
+const (
+	BackoffConstant    = "constant"    // the same delay before every attempt
+	BackoffExponential = "exponential" // delay doubles after every attempt
+)
+
+// Retry is a policy for repeating requests that fail with a network
+// error or one of the listed status codes.
+type Retry struct {
+	Attempts       int           // total number of attempts, including the first one
+	Backoff        string        // backoff strategy
+	Initial        time.Duration // delay before the second attempt
+	MaxDelay       time.Duration // limits exponential delays, no limit if 0
+	OnStatus       []int         // status codes that are retried
+	OnNetworkError bool          // whether network errors are retried
+}
+
+// replaced in tests
+var sleep = time.Sleep
+
+func parseRetry(block *bcl.BlockNode) (*Retry, error) {
+	r := &Retry{
+		Attempts: 3,
+		Backoff:  BackoffExponential,
+		Initial:  100 * time.Millisecond,
+		OnStatus: make([]int, 0),
+	}
+
+	if len(block.Driver.Text) != 0 || len(block.Name.Text) != 0 || len(block.Blocks) != 0 {
+		return nil, fmt.Errorf("invalid `retry` block")
+	}
+
+	for _, expression := range block.Expressions {
+		switch {
+		case string(expression.Field.Text) == "on_status":
+			listNode, err := expression.ValueAsList()
+			if err != nil {
+				return nil, err
+			}
+			for _, node := range listNode.Nodes {
+				stringNode, ok := node.(*bcl.StringNode)
+				if !ok {
+					return nil, fmt.Errorf("invalid `on_status` value %s", node)
+				}
+				code, err := strconv.Atoi(string(stringNode.Text))
+				if err != nil || code < 100 || code > 599 {
+					return nil, fmt.Errorf("invalid `on_status` value %q", stringNode.Text)
+				}
+				r.OnStatus = append(r.OnStatus, code)
+			}
+			continue
+		}
+
+		value, err := expression.ValueAsString()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case string(expression.Field.Text) == "attempts":
+			attempts, err := strconv.Atoi(value)
+			if err != nil || attempts < 1 {
+				return nil, fmt.Errorf("invalid `attempts` value %q", value)
+			}
+			r.Attempts = attempts
+		case string(expression.Field.Text) == "backoff":
+			if value != BackoffConstant && value != BackoffExponential {
+				return nil, fmt.Errorf("invalid `backoff` value %q, allowed values are %q and %q",
+					value, BackoffConstant, BackoffExponential)
+			}
+			r.Backoff = value
+		case string(expression.Field.Text) == "initial":
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("invalid `initial` value %q", value)
+			}
+			r.Initial = d
+		case string(expression.Field.Text) == "max_delay":
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("invalid `max_delay` value %q", value)
+			}
+			r.MaxDelay = d
+		case string(expression.Field.Text) == "on_network_error":
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return nil, fmt.Errorf("invalid `on_network_error` value %q", value)
+			}
+			r.OnNetworkError = b
+		default:
+			return nil, fmt.Errorf("unknown retry input %q", expression.Field.Text)
+		}
+	}
+
+	if len(r.OnStatus) == 0 && !r.OnNetworkError {
+		return nil, fmt.Errorf("`retry` requires `on_status` or `on_network_error`")
+	}
+
+	return r, nil
+}
+
+// delay returns how long to wait after the failed attempt, attempts
+// are counted from 1
+func (r *Retry) delay(attempt int) time.Duration {
+	if r.Backoff == BackoffConstant {
+		return r.Initial
+	}
+
+	d := r.Initial
+	for i := 1; i < attempt; i++ {
+		// stop doubling before the duration overflows
+		if d > math.MaxInt64/2 {
+			break
+		}
+		d *= 2
+		if r.MaxDelay > 0 && d >= r.MaxDelay {
+			break
+		}
+	}
+
+	if r.MaxDelay > 0 && d > r.MaxDelay {
+		return r.MaxDelay
+	}
+	return d
+}
+
+// retryable checks whether the attempt should be repeated
+func (r *Retry) retryable(resp *http.Response, err error) bool {
+	if err != nil {
+		return r.OnNetworkError
+	}
+
+	for _, code := range r.OnStatus {
+		if resp.StatusCode == code {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+package http_step
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bluebookrun/bluebook/bcl/bcltest"
+	"github.com/bluebookrun/bluebook/resource"
+)
+
+func TestRetryDelay(t *testing.T) {
+	r := &Retry{Backoff: BackoffExponential, Initial: 100 * time.Millisecond}
+	assert.Equal(t, 100*time.Millisecond, r.delay(1))
+	assert.Equal(t, 200*time.Millisecond, r.delay(2))
+	assert.Equal(t, 400*time.Millisecond, r.delay(3))
+
+	// without max_delay doubling stops before the duration overflows
+	assert.True(t, r.delay(100) > 0)
+	assert.True(t, r.delay(1000) >= r.delay(100))
+
+	r.MaxDelay = 300 * time.Millisecond
+	assert.Equal(t, 300*time.Millisecond, r.delay(3))
+	assert.Equal(t, 300*time.Millisecond, r.delay(50))
+
+	r.Backoff = BackoffConstant
+	assert.Equal(t, 100*time.Millisecond, r.delay(3))
+}
+
+func TestRetry(t *testing.T) {
+	delays := make([]time.Duration, 0)
+	sleep = func(d time.Duration) { delays = append(delays, d) }
+	defer func() { sleep = time.Sleep }()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	step, err := New(bcltest.Block(t, `resource "http_step" "retry" {
+		method = "POST"
+		url = "`+server.URL+`"
+		body = "data"
+
+		retry {
+			attempts = 3, initial = "200ms"
+			on_status = [502, 503]
+		}
+	}`))
+	assert.Nil(t, err)
+
+	ctx := resource.NewExecutionContext()
+	assert.Nil(t, step.Exec(ctx))
+	assert.Equal(t, 200, ctx.CurrentResponse.StatusCode)
+	assert.Equal(t, []time.Duration{200 * time.Millisecond, 400 * time.Millisecond}, delays)
+
+	statusCodes := make([]int, 0)
+	for _, attempt := range ctx.CurrentResult.Attempts {
+		statusCodes = append(statusCodes, attempt.StatusCode)
+	}
+	assert.Equal(t, []int{502, 502, 200}, statusCodes)
+
+	// attempts are exhausted, the last response is kept for assertions
+	requests = 0
+	step.Retry.Attempts = 2
+	assert.Nil(t, step.Exec(ctx))
+	assert.Equal(t, 502, ctx.CurrentResponse.StatusCode)
+	assert.Equal(t, 2, len(ctx.CurrentResult.Attempts))
+}
+
+func TestRetryNetworkError(t *testing.T) {
+	sleep = func(time.Duration) {}
+	defer func() { sleep = time.Sleep }()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	url := server.URL
+	server.Close()
+
+	step, err := New(bcltest.Block(t, `resource "http_step" "retry" {
+		method = "GET"
+		url = "`+url+`"
+
+		retry {
+			attempts = 4
+			backoff = "constant"
+			on_network_error = true
+		}
+	}`))
+	assert.Nil(t, err)
+
+	ctx := resource.NewExecutionContext()
+	err = step.Exec(ctx)
+	assert.NotNil(t, err)
+	assert.Contains(t, err.Error(), "(4 attempts)")
+	assert.Equal(t, 4, len(ctx.CurrentResult.Attempts))
+	assert.NotEqual(t, "", ctx.CurrentResult.Attempts[0].Error)
+}
+
+func TestRetryValidation(t *testing.T) {
+	invalid := []string{
+		`retry { attempts = 3 }`,
+		`retry { attempts = 0, on_network_error = true }`,
+		`retry { backoff = "linear", on_network_error = true }`,
+		`retry { initial = "soon", on_network_error = true }`,
+		`retry { on_status = [600] }`,
+		`retry { on_status = "502" }`,
+		`retry { on_network_error = maybe }`,
+		`retry { tries = 3, on_network_error = true }`,
+		`retry { on_network_error = true } retry { on_network_error = true }`,
+	}
+
+	for _, text := range invalid {
+		_, err := New(bcltest.Block(t, `resource "http_step" "a" { method = "GET", url = "/", `+text+` }`))
+		assert.NotNil(t, err, text)
+	}
+}
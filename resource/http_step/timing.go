@@ -0,0 +1,76 @@
+package http_step
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptrace"
+	"sync"
+	"time"
+
+	"github.com/bluebookrun/bluebook/resource"
+)
+
+// tracer records timings of a single request with httptrace. Trace
+// hooks may be called from transport goroutines, e.g. when dialing
+// several addresses.
+type tracer struct {
+	mu           sync.Mutex
+	start        time.Time
+	dnsStart     time.Time
+	connectStart time.Time
+	tlsStart     time.Time
+	timings      resource.Timings
+}
+
+// trace returns request that reports its timings to the tracer, start
+// of the request is the time trace is called
+func (t *tracer) trace(req *http.Request) *http.Request {
+	t.start = time.Now()
+
+	since := func(start *time.Time, phase *time.Duration) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		if !start.IsZero() {
+			*phase = time.Since(*start)
+		}
+	}
+
+	started := func(start *time.Time) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+		*start = time.Now()
+	}
+
+	return req.WithContext(httptrace.WithClientTrace(req.Context(), &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			started(&t.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			since(&t.dnsStart, &t.timings.DNS)
+		},
+		ConnectStart: func(network, addr string) {
+			started(&t.connectStart)
+		},
+		ConnectDone: func(network, addr string, err error) {
+			since(&t.connectStart, &t.timings.Connect)
+		},
+		TLSHandshakeStart: func() {
+			started(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			since(&t.tlsStart, &t.timings.TLS)
+		},
+		GotFirstResponseByte: func() {
+			since(&t.start, &t.timings.TTFB)
+		},
+	}))
+}
+
+// finish returns timings, total time ends when finish is called
+func (t *tracer) finish() resource.Timings {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.timings.Total = time.Since(t.start)
+	return t.timings
+}
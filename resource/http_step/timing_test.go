@@ -0,0 +1,49 @@
+package http_step
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bluebookrun/bluebook/bcl/bcltest"
+	"github.com/bluebookrun/bluebook/resource"
+)
+
+func TestTimings(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(20 * time.Millisecond)
+		w.WriteHeader(http.StatusOK)
+		w.(http.Flusher).Flush()
+
+		time.Sleep(20 * time.Millisecond)
+		io.WriteString(w, "done")
+	}))
+	defer server.Close()
+
+	step, err := New(bcltest.Block(t, `resource "http_step" "slow" {
+		method = "GET"
+		url = "`+server.URL+`"
+		insecure_skip_verify = true
+	}`))
+	assert.Nil(t, err)
+
+	ctx := resource.NewExecutionContext()
+	assert.Nil(t, step.Exec(ctx))
+
+	timings := ctx.CurrentResult.Timings
+	assert.True(t, timings.Connect > 0, timings.String())
+	assert.True(t, timings.TLS > 0, timings.String())
+	assert.True(t, timings.TTFB >= 20*time.Millisecond, timings.String())
+	assert.True(t, timings.Total >= timings.TTFB+20*time.Millisecond, timings.String())
+
+	// connection is reused by the next step
+	assert.Nil(t, step.Exec(ctx))
+	timings = ctx.CurrentResult.Timings
+	assert.Equal(t, time.Duration(0), timings.Connect, timings.String())
+	assert.Equal(t, time.Duration(0), timings.TLS, timings.String())
+	assert.True(t, timings.TTFB >= 20*time.Millisecond, timings.String())
+}
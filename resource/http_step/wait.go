@@ -0,0 +1,101 @@
+package http_step
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/bluebookrun/bluebook/bcl"
+	"github.com/bluebookrun/bluebook/resource"
+	"github.com/bluebookrun/bluebook/resource/http_auth"
+	"github.com/bluebookrun/bluebook/resource/http_client"
+)
+
+// WaitUntil repeats the request until all assertions pass, e.g. to
+// poll status of an asynchronous job.
+type WaitUntil struct {
+	Timeout  time.Duration // how long to poll before the step fails
+	Interval time.Duration // delay between requests
+}
+
+// replaced in tests
+var now = time.Now
+
+func parseWaitUntil(block *bcl.BlockNode) (*WaitUntil, error) {
+	w := &WaitUntil{
+		Timeout:  30 * time.Second,
+		Interval: time.Second,
+	}
+
+	if len(block.Driver.Text) != 0 || len(block.Name.Text) != 0 || len(block.Blocks) != 0 {
+		return nil, fmt.Errorf("invalid `wait_until` block")
+	}
+
+	for _, expression := range block.Expressions {
+		value, err := expression.ValueAsString()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case string(expression.Field.Text) == "timeout":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid `timeout` value %q", value)
+			}
+			w.Timeout = d
+		case string(expression.Field.Text) == "interval":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return nil, fmt.Errorf("invalid `interval` value %q", value)
+			}
+			w.Interval = d
+		default:
+			return nil, fmt.Errorf("unknown wait_until input %q", expression.Field.Text)
+		}
+	}
+
+	return w, nil
+}
+
+// poll repeats the request until all assertions pass. Request errors
+// are treated as failures, so polling continues while the endpoint is
+// unavailable.
+func (r *Resource) poll(ctx *resource.ExecutionContext, client *http.Client, signer http_auth.Signer, options *http_client.Options, method string, url string) error {
+	deadline := now().Add(r.WaitUntil.Timeout)
+
+	for poll := 1; ; poll++ {
+		failures := make([]error, 0)
+
+		if err := r.request(ctx, client, signer, options, method, url); err != nil {
+			failures = append(failures, err)
+		} else {
+			for _, proxy := range r.Assertions {
+				if err := proxy.Resource.Exec(ctx); err != nil {
+					failures = append(failures, err)
+				}
+			}
+		}
+
+		ctx.CurrentResult.Polls = poll
+		if len(failures) == 0 {
+			if poll > 1 {
+				fmt.Printf("    condition met after %d requests\n", poll)
+			}
+			return nil
+		}
+
+		if now().Add(r.WaitUntil.Interval).After(deadline) {
+			messages := make([]string, 0, len(failures))
+			for _, failure := range failures {
+				messages = append(messages, "    - "+failure.Error())
+			}
+
+			return fmt.Errorf("condition not met after %d requests in %s, last failures:\n%s",
+				poll, r.WaitUntil.Timeout, strings.Join(messages, "\n"))
+		}
+
+		sleep(r.WaitUntil.Interval)
+	}
+}
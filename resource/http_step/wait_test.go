@@ -0,0 +1,100 @@
+package http_step
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bluebookrun/bluebook/bcl/bcltest"
+	"github.com/bluebookrun/bluebook/evaluator/proxy"
+	"github.com/bluebookrun/bluebook/resource"
+)
+
+// bodyAssertion checks that response body equals the expected value
+type bodyAssertion struct {
+	expected string
+}
+
+func (a *bodyAssertion) Link(ctx *resource.ExecutionContext) error { return nil }
+func (a *bodyAssertion) GetAttribute(name string) *string          { return nil }
+
+func (a *bodyAssertion) Exec(ctx *resource.ExecutionContext) error {
+	if string(ctx.CurrentResponseBody) != a.expected {
+		return fmt.Errorf("body %q != %q", ctx.CurrentResponseBody, a.expected)
+	}
+	return nil
+}
+
+func fakeClock() func() {
+	clock := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	sleep = func(d time.Duration) { clock = clock.Add(d) }
+
+	return func() {
+		now = time.Now
+		sleep = time.Sleep
+	}
+}
+
+func TestWaitUntil(t *testing.T) {
+	defer fakeClock()()
+
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests < 3 {
+			fmt.Fprint(w, "running")
+			return
+		}
+		fmt.Fprint(w, "done")
+	}))
+	defer server.Close()
+
+	step, err := New(bcltest.Block(t, `resource "http_step" "job" {
+		method = "GET"
+		url = "`+server.URL+`"
+
+		wait_until {
+			timeout = "10s"
+			interval = "2s"
+		}
+	}`))
+	assert.Nil(t, err)
+
+	step.Assertions = []*proxy.Proxy{
+		{Resource: &bodyAssertion{"done"}},
+	}
+
+	ctx := resource.NewExecutionContext()
+	assert.Nil(t, step.Exec(ctx))
+	assert.Equal(t, 3, ctx.CurrentResult.Polls)
+	assert.Equal(t, "done", string(ctx.CurrentResponseBody))
+
+	// deadline expires
+	requests = 0
+	step.Assertions = append(step.Assertions, &proxy.Proxy{Resource: &bodyAssertion{"failed"}})
+
+	err = step.Exec(ctx)
+	assert.NotNil(t, err)
+	assert.Equal(t, 6, ctx.CurrentResult.Polls)
+	assert.Equal(t, `condition not met after 6 requests in 10s, last failures:
+    - body "done" != "failed"`, err.Error())
+}
+
+func TestWaitUntilValidation(t *testing.T) {
+	invalid := []string{
+		`wait_until { timeout = "soon" }`,
+		`wait_until { interval = "0s" }`,
+		`wait_until { every = "1s" }`,
+		`wait_until { } wait_until { }`,
+	}
+
+	for _, text := range invalid {
+		_, err := New(bcltest.Block(t, `resource "http_step" "a" { method = "GET", url = "/", `+text+` }`))
+		assert.NotNil(t, err, text)
+	}
+}
@@ -5,16 +5,42 @@ import (
 	"github.com/bluebookrun/bluebook/bcl"
 	"github.com/bluebookrun/bluebook/evaluator/proxy"
 	"github.com/bluebookrun/bluebook/resource"
+	"github.com/bluebookrun/bluebook/resource/http_auth"
+	"github.com/bluebookrun/bluebook/resource/http_client"
 	"github.com/google/uuid"
 )
 
 type Resource struct {
-	Node       *bcl.BlockNode
-	Steps      []*proxy.Proxy
-	attributes map[string]string
+	Node          *bcl.BlockNode
+	Steps         []*proxy.Proxy
+	ClientOptions *http_client.Options // defaults for all steps of the test
+	Cookies       string               // cookie mode, settings default if empty
+	Auth          *http_auth.Auth      // default authentication of steps, nil if not set
+	attributes    map[string]string
 }
 
 func (d *Resource) Exec(ctx *resource.ExecutionContext) error {
+	settings := ctx.ClientOptions
+	ctx.ClientOptions = settings.Merge(d.ClientOptions)
+	defer func() { ctx.ClientOptions = settings }()
+
+	cookies := d.Cookies
+	if cookies == "" {
+		cookies = ctx.Cookies
+	}
+
+	jar := ctx.CookieJar
+	ctx.CookieJar = ctx.Clients.NewCookieJar(cookies)
+	defer func() { ctx.CookieJar = jar }()
+
+	auth := ctx.Auth
+	ctx.Auth = d.Auth
+	defer func() { ctx.Auth = auth }()
+
+	test := ctx.CurrentTest
+	ctx.CurrentTest = d.Node.Ref()
+	defer func() { ctx.CurrentTest = test }()
+
 	for _, proxy := range d.Steps {
 		if err := proxy.Resource.Exec(ctx); err != nil {
 			return err
@@ -42,8 +68,9 @@ func (d *Resource) GetAttribute(name string) *string {
 
 func New(node *bcl.BlockNode) (*Resource, error) {
 	d := &Resource{
-		Node:  node,
-		Steps: make([]*proxy.Proxy, 0),
+		Node:          node,
+		Steps:         make([]*proxy.Proxy, 0),
+		ClientOptions: &http_client.Options{},
 		attributes: map[string]string{
 			"id": uuid.New().String(),
 		},
@@ -66,8 +93,46 @@ func New(node *bcl.BlockNode) (*Resource, error) {
 					Type: proxy.ProxyDriver,
 				})
 			}
+		case string(expression.Field.Text) == "cookies":
+			value, err := expression.ValueAsString()
+			if err != nil {
+				return nil, err
+			}
+			if err := http_client.ValidateCookies(value); err != nil {
+				return nil, err
+			}
+			d.Cookies = value
+		default:
+			ok, err := d.ClientOptions.Parse(expression)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				return nil, fmt.Errorf("unknown http_test input %q", expression.Field.Text)
+			}
 		}
 	}
 
+	for _, block := range node.Blocks {
+		switch {
+		case string(block.Id.Text) == "auth":
+			if d.Auth != nil {
+				return nil, fmt.Errorf("only one `auth` block is allowed")
+			}
+
+			auth, err := http_auth.Parse(block)
+			if err != nil {
+				return nil, err
+			}
+			d.Auth = auth
+		default:
+			return nil, fmt.Errorf("unknown block %q", block.Id.Text)
+		}
+	}
+
+	if err := d.ClientOptions.Validate(); err != nil {
+		return nil, err
+	}
+
 	return d, nil
 }
@@ -0,0 +1,29 @@
+package http_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bluebookrun/bluebook/bcl/bcltest"
+)
+
+func TestInputs(t *testing.T) {
+	r, err := New(bcltest.Block(t, `resource "http_test" "a" {
+		steps = ["${http_step.a.id}"]
+		timeout = "5s"
+		follow_redirects = "false"
+	}`))
+	assert.Nil(t, err)
+	assert.Equal(t, 1, len(r.Steps))
+	assert.Equal(t, "5s", r.ClientOptions.Timeout.String())
+
+	_, err = New(bcltest.Block(t, `resource "http_test" "a" { timeot = "5s" }`))
+	assert.EqualError(t, err, `unknown http_test input "timeot"`)
+
+	_, err = New(bcltest.Block(t, `resource "http_test" "a" { timeout = "soon" }`))
+	assert.NotNil(t, err)
+
+	_, err = New(bcltest.Block(t, `resource "http_test" "a" { steps = [], retry { attempts = "3" } }`))
+	assert.EqualError(t, err, `unknown block "retry"`)
+}
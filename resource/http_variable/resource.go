@@ -1,24 +1,25 @@
 package http_variable
 
 import (
-	"encoding/json"
 	"fmt"
+	"strings"
 
-	"github.com/firewut/go-json-map"
 	"github.com/google/uuid"
 
 	"github.com/bluebookrun/bluebook/bcl"
 	"github.com/bluebookrun/bluebook/interpolator"
+	"github.com/bluebookrun/bluebook/jsonpath"
+	"github.com/bluebookrun/bluebook/markup"
 	"github.com/bluebookrun/bluebook/resource"
 )
 
 type Resource struct {
-	Node         *bcl.BlockNode
-	attributes   map[string]string
-	source       string
-	property     string
-	variable     string
-	numeric_type string
+	Node       *bcl.BlockNode
+	attributes map[string]string
+	source     string
+	property   string
+	attribute  string // cookie attribute, element attribute of xml_body and html_body sources, or media type or parameter of header
+	variable   string
 }
 
 func New(node *bcl.BlockNode) (*Resource, error) {
@@ -29,6 +30,10 @@ func New(node *bcl.BlockNode) (*Resource, error) {
 		},
 	}
 
+	if len(node.Blocks) != 0 {
+		return nil, fmt.Errorf("unknown block %q", node.Blocks[0].Id.Text)
+	}
+
 	for _, expression := range node.Expressions {
 		switch {
 		case string(expression.Field.Text) == "source":
@@ -49,12 +54,15 @@ func New(node *bcl.BlockNode) (*Resource, error) {
 				return nil, err
 			}
 			r.property = value
-		case string(expression.Field.Text) == "numeric_type":
+		case string(expression.Field.Text) == "attribute":
 			value, err := expression.ValueAsString()
 			if err != nil {
 				return nil, err
 			}
-			r.numeric_type = value
+			r.attribute = value
+		case string(expression.Field.Text) == "numeric_type":
+			// numbers used to be captured as formatted strings
+			return nil, fmt.Errorf("`numeric_type` is no longer supported, JSON numbers are captured as numbers, remove it")
 		}
 	}
 
@@ -78,18 +86,32 @@ func validateResource(r *Resource) error {
 		return fmt.Errorf("`property` is required")
 	}
 
-	if r.source == "json_body" {
-		if r.numeric_type == "" {
-			r.numeric_type = "int"
-		}
+	switch r.source {
+	case "json_body", "xml_body", "html_body", "header", "cookie":
+	default:
+		return fmt.Errorf("invalid `source` value, allowed values are 'json_body', 'xml_body', 'html_body', 'header' and 'cookie'")
+	}
 
-		if r.numeric_type != "int" && r.numeric_type != "float" {
-			return fmt.Errorf("invalid `numeric_type` value, allowed values are 'int' and 'float'")
+	if (r.source == "xml_body" || r.source == "html_body") && !strings.Contains(r.property, "${") {
+		if _, err := markup.Compile(r.source, r.property); err != nil {
+			return fmt.Errorf("invalid `property` value: %s", err.Error())
 		}
 	}
 
-	if r.source != "json_body" && r.source != "header" {
-		return fmt.Errorf("invalid `source` value, allowed values are 'json_body' and 'header'")
+	if r.attribute != "" && r.source != "cookie" && r.source != "header" && r.source != "xml_body" && r.source != "html_body" {
+		return fmt.Errorf("`attribute` is supported by cookie, header, xml_body and html_body sources only")
+	}
+
+	if r.attribute != "" && r.source == "cookie" {
+		valid := false
+		for _, attribute := range resource.CookieAttributes {
+			if r.attribute == attribute {
+				valid = true
+			}
+		}
+		if !valid {
+			return fmt.Errorf("invalid `attribute` value %q", r.attribute)
+		}
 	}
 
 	return nil
@@ -123,13 +145,37 @@ func (r *Resource) Exec(ctx *resource.ExecutionContext) error {
 	}
 
 	if r.source == "header" {
-		value, ok := httpResponse.Header[r.property]
-		if !ok {
+		// header names are case-insensitive, the first value of repeated
+		// headers is captured
+		values := httpResponse.Header.Values(property)
+		if len(values) == 0 {
 			return nil
 		}
-		ctx.SetVariable(variable, value[0])
+
+		value, err := resource.HeaderAttribute(values[0], r.attribute)
+		if err != nil {
+			return err
+		}
+		ctx.SetVariable(variable, value)
 	} else if r.source == "json_body" {
-		value, err := captureJsonVariable(httpBody, property, r.numeric_type == "int")
+		value, err := captureJsonVariable(httpBody, property)
+		if err != nil {
+			return err
+		}
+		ctx.SetVariable(variable, value)
+	} else if r.source == "xml_body" || r.source == "html_body" {
+		value, err := captureMarkupVariable(r.source, httpBody, property, r.attribute)
+		if err != nil {
+			return err
+		}
+		ctx.SetVariable(variable, value)
+	} else if r.source == "cookie" {
+		cookie := resource.FindCookie(httpResponse, property)
+		if cookie == nil {
+			return nil
+		}
+
+		value, err := resource.CookieAttribute(cookie, r.attribute)
 		if err != nil {
 			return err
 		}
@@ -141,39 +187,35 @@ func (r *Resource) Exec(ctx *resource.ExecutionContext) error {
 	return nil
 }
 
-func captureJsonVariable(body []byte, path string, intNumbers bool) (string, error) {
-	var jsonData map[string]interface{}
-
-	err := json.Unmarshal(body, &jsonData)
+// captures JSON value as it is, numbers are decoded as int64 or float64.
+// Paths with wildcards, slices or filters capture a list of all values.
+func captureJsonVariable(body []byte, path string) (interface{}, error) {
+	document, err := resource.DecodeJSON(body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	property, err := gjm.GetProperty(jsonData, path)
+	return jsonpath.Get(document, path)
+}
+
+// captures text or attribute of the element selected from XML or HTML
+// body, selectors matching many elements capture a list of all values
+func captureMarkupVariable(source string, body []byte, property string, attribute string) (interface{}, error) {
+	selector, err := markup.Compile(source, property)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	var value string
-	switch property := property.(type) {
-	case bool:
-		if property {
-			value = "true"
-		} else {
-			value = "false"
-		}
-	case string:
-		value = property
-	case float64:
-		// both ints and floats end up here.
-		if intNumbers {
-			value = fmt.Sprintf("%.0f", property)
-		} else {
-			value = fmt.Sprintf("%f", property)
-		}
-	default:
-		return "", fmt.Errorf("complex JSON fields are not supported")
+	values, err := selector.Select(body, attribute)
+	if err != nil {
+		return nil, err
 	}
 
-	return value, nil
+	switch len(values) {
+	case 0:
+		return nil, fmt.Errorf("selector %q selected no values", property)
+	case 1:
+		return values[0], nil
+	}
+	return values, nil
 }
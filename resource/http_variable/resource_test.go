@@ -2,6 +2,7 @@ package http_variable
 
 import (
 	"github.com/bluebookrun/bluebook/bcl"
+	"github.com/bluebookrun/bluebook/bcl/bcltest"
 	"github.com/bluebookrun/bluebook/resource"
 	"github.com/stretchr/testify/assert"
 	"net/http"
@@ -9,13 +10,13 @@ import (
 )
 
 type validationTestCase struct {
-	source       string
-	property     string
-	variable     string
-	numeric_type string
-	valid        bool
-	inCtx        *resource.ExecutionContext
-	outVars      map[string]string
+	source    string
+	property  string
+	attribute string
+	variable  string
+	valid     bool
+	inCtx     *resource.ExecutionContext
+	outVars   map[string]interface{}
 }
 
 var execTestCases = []validationTestCase{
@@ -25,14 +26,14 @@ var execTestCases = []validationTestCase{
 		variable: "v",
 		valid:    true,
 		inCtx: &resource.ExecutionContext{
-			Variables: make(map[string]string),
+			Variables: make(map[string]interface{}),
 			CurrentResponse: &http.Response{
 				Header: http.Header{
 					"Content-Type": []string{"type"},
 				},
 			},
 		},
-		outVars: map[string]string{
+		outVars: map[string]interface{}{
 			"v": "type",
 		},
 	},
@@ -42,12 +43,12 @@ var execTestCases = []validationTestCase{
 		variable: "v",
 		valid:    true,
 		inCtx: &resource.ExecutionContext{
-			Variables: make(map[string]string),
+			Variables: make(map[string]interface{}),
 			CurrentResponse: &http.Response{
 				Header: http.Header{},
 			},
 		},
-		outVars: map[string]string{},
+		outVars: map[string]interface{}{},
 	},
 	{
 		source:   "json_body",
@@ -55,10 +56,10 @@ var execTestCases = []validationTestCase{
 		variable: "v",
 		valid:    true,
 		inCtx: &resource.ExecutionContext{
-			Variables:           make(map[string]string),
+			Variables:           make(map[string]interface{}),
 			CurrentResponseBody: []byte(`{}`),
 		},
-		outVars: map[string]string{},
+		outVars: map[string]interface{}{},
 	},
 	{
 		source:   "json_body",
@@ -66,11 +67,11 @@ var execTestCases = []validationTestCase{
 		variable: "v",
 		valid:    true,
 		inCtx: &resource.ExecutionContext{
-			Variables:           make(map[string]string),
+			Variables:           make(map[string]interface{}),
 			CurrentResponse:     &http.Response{},
 			CurrentResponseBody: []byte(`{"data": "value"}`),
 		},
-		outVars: map[string]string{
+		outVars: map[string]interface{}{
 			"v": "value",
 		},
 	},
@@ -80,11 +81,11 @@ var execTestCases = []validationTestCase{
 		variable: "v",
 		valid:    true,
 		inCtx: &resource.ExecutionContext{
-			Variables:           make(map[string]string),
+			Variables:           make(map[string]interface{}),
 			CurrentResponse:     &http.Response{},
 			CurrentResponseBody: []byte(`{"data": { "list": ["value"] }}`),
 		},
-		outVars: map[string]string{
+		outVars: map[string]interface{}{
 			"v": "value",
 		},
 	},
@@ -94,11 +95,11 @@ var execTestCases = []validationTestCase{
 		variable: "v",
 		valid:    false,
 		inCtx: &resource.ExecutionContext{
-			Variables:           make(map[string]string),
+			Variables:           make(map[string]interface{}),
 			CurrentResponse:     &http.Response{},
 			CurrentResponseBody: []byte(`{"data": { "list": ["value"] }}`),
 		},
-		outVars: map[string]string{},
+		outVars: map[string]interface{}{},
 	},
 	{
 		source:   "json_body",
@@ -106,11 +107,11 @@ var execTestCases = []validationTestCase{
 		variable: "v",
 		valid:    false,
 		inCtx: &resource.ExecutionContext{
-			Variables:           make(map[string]string),
+			Variables:           make(map[string]interface{}),
 			CurrentResponse:     &http.Response{},
 			CurrentResponseBody: []byte(`{"data": { "list": ["value"] }}`),
 		},
-		outVars: map[string]string{},
+		outVars: map[string]interface{}{},
 	},
 	{
 		source:   "json_body",
@@ -118,12 +119,12 @@ var execTestCases = []validationTestCase{
 		variable: "v",
 		valid:    true,
 		inCtx: &resource.ExecutionContext{
-			Variables:           make(map[string]string),
+			Variables:           make(map[string]interface{}),
 			CurrentResponse:     &http.Response{},
 			CurrentResponseBody: []byte(`{"data": true}`),
 		},
-		outVars: map[string]string{
-			"v": "true",
+		outVars: map[string]interface{}{
+			"v": true,
 		},
 	},
 	{
@@ -132,39 +133,43 @@ var execTestCases = []validationTestCase{
 		variable: "v",
 		valid:    true,
 		inCtx: &resource.ExecutionContext{
-			Variables:           make(map[string]string),
+			Variables:           make(map[string]interface{}),
 			CurrentResponse:     &http.Response{},
 			CurrentResponseBody: []byte(`{"data": false}`),
 		},
-		outVars: map[string]string{
-			"v": "false",
+		outVars: map[string]interface{}{
+			"v": false,
 		},
 	},
 	{
 		source:   "json_body",
 		property: "data",
 		variable: "v",
-		valid:    false,
+		valid:    true,
 		inCtx: &resource.ExecutionContext{
-			Variables:           make(map[string]string),
+			Variables:           make(map[string]interface{}),
 			CurrentResponse:     &http.Response{},
 			CurrentResponseBody: []byte(`{"data": {}}`),
 		},
-		outVars: map[string]string{},
+		outVars: map[string]interface{}{
+			"v": map[string]interface{}{},
+		},
 	},
 	{
-		source:       "json_body",
-		property:     "data",
-		variable:     "v",
-		valid:        true,
-		numeric_type: "int",
+		source:   "json_body",
+		property: "data",
+		variable: "v",
+		valid:    true,
 		inCtx: &resource.ExecutionContext{
-			Variables:           make(map[string]string),
+			Variables:           make(map[string]interface{}),
 			CurrentResponse:     &http.Response{},
-			CurrentResponseBody: []byte(`{"data": 123}`),
+			CurrentResponseBody: []byte(`{"data": [{"id": "a"}, {"id": "b"}]}`),
 		},
-		outVars: map[string]string{
-			"v": "123",
+		outVars: map[string]interface{}{
+			"v": []interface{}{
+				map[string]interface{}{"id": "a"},
+				map[string]interface{}{"id": "b"},
+			},
 		},
 	},
 	{
@@ -173,12 +178,40 @@ var execTestCases = []validationTestCase{
 		variable: "v",
 		valid:    true,
 		inCtx: &resource.ExecutionContext{
-			Variables:           make(map[string]string),
+			Variables:           make(map[string]interface{}),
 			CurrentResponse:     &http.Response{},
 			CurrentResponseBody: []byte(`{"data": 123}`),
 		},
-		outVars: map[string]string{
-			"v": "123.000000",
+		outVars: map[string]interface{}{
+			"v": int64(123),
+		},
+	},
+	{
+		source:   "json_body",
+		property: "data",
+		variable: "v",
+		valid:    true,
+		inCtx: &resource.ExecutionContext{
+			Variables:           make(map[string]interface{}),
+			CurrentResponse:     &http.Response{},
+			CurrentResponseBody: []byte(`{"data": 9007199254740993}`),
+		},
+		outVars: map[string]interface{}{
+			"v": int64(9007199254740993),
+		},
+	},
+	{
+		source:   "json_body",
+		property: "data",
+		variable: "v",
+		valid:    true,
+		inCtx: &resource.ExecutionContext{
+			Variables:           make(map[string]interface{}),
+			CurrentResponse:     &http.Response{},
+			CurrentResponseBody: []byte(`{"data": 123.5}`),
+		},
+		outVars: map[string]interface{}{
+			"v": 123.5,
 		},
 	},
 	{
@@ -187,11 +220,176 @@ var execTestCases = []validationTestCase{
 		variable: "v",
 		valid:    false,
 		inCtx: &resource.ExecutionContext{
-			Variables:           make(map[string]string),
+			Variables:           make(map[string]interface{}),
 			CurrentResponse:     &http.Response{},
 			CurrentResponseBody: []byte(`aaa`),
 		},
-		outVars: map[string]string{},
+		outVars: map[string]interface{}{},
+	},
+	{
+		source:   "json_body",
+		property: "$[1].id",
+		variable: "v",
+		valid:    true,
+		inCtx: &resource.ExecutionContext{
+			Variables:           make(map[string]interface{}),
+			CurrentResponse:     &http.Response{},
+			CurrentResponseBody: []byte(`[{"id": 1}, {"id": 2}]`),
+		},
+		outVars: map[string]interface{}{
+			"v": int64(2),
+		},
+	},
+	{
+		source:   "json_body",
+		property: "$.items[?(@.active == true)].name",
+		variable: "v",
+		valid:    true,
+		inCtx: &resource.ExecutionContext{
+			Variables:           make(map[string]interface{}),
+			CurrentResponse:     &http.Response{},
+			CurrentResponseBody: []byte(`{"items": [{"name": "a", "active": true}, {"name": "b"}, {"name": "c", "active": true}]}`),
+		},
+		outVars: map[string]interface{}{
+			"v": []interface{}{"a", "c"},
+		},
+	},
+	{
+		source:   "cookie",
+		property: "session",
+		variable: "v",
+		valid:    true,
+		inCtx: &resource.ExecutionContext{
+			Variables: make(map[string]interface{}),
+			CurrentResponse: &http.Response{
+				Header: http.Header{
+					"Set-Cookie": []string{"session=secret; HttpOnly"},
+				},
+			},
+		},
+		outVars: map[string]interface{}{
+			"v": "secret",
+		},
+	},
+	{
+		source:    "cookie",
+		property:  "session",
+		attribute: "http_only",
+		variable:  "v",
+		valid:     true,
+		inCtx: &resource.ExecutionContext{
+			Variables: make(map[string]interface{}),
+			CurrentResponse: &http.Response{
+				Header: http.Header{
+					"Set-Cookie": []string{"session=secret; HttpOnly"},
+				},
+			},
+		},
+		outVars: map[string]interface{}{
+			"v": true,
+		},
+	},
+	{
+		source:   "cookie",
+		property: "session",
+		variable: "v",
+		valid:    true,
+		inCtx: &resource.ExecutionContext{
+			Variables:       make(map[string]interface{}),
+			CurrentResponse: &http.Response{Header: http.Header{}},
+		},
+		outVars: map[string]interface{}{},
+	},
+	{
+		source:   "xml_body",
+		property: "//order[@status='paid']/id",
+		variable: "v",
+		valid:    true,
+		inCtx: &resource.ExecutionContext{
+			Variables:           make(map[string]interface{}),
+			CurrentResponse:     &http.Response{},
+			CurrentResponseBody: []byte(`<orders><order status="paid"><id>7</id></order><order><id>8</id></order></orders>`),
+		},
+		outVars: map[string]interface{}{
+			"v": "7",
+		},
+	},
+	{
+		source:    "xml_body",
+		property:  "//order",
+		attribute: "status",
+		variable:  "v",
+		valid:     true,
+		inCtx: &resource.ExecutionContext{
+			Variables:           make(map[string]interface{}),
+			CurrentResponse:     &http.Response{},
+			CurrentResponseBody: []byte(`<orders><order status="paid"/><order status="new"/></orders>`),
+		},
+		outVars: map[string]interface{}{
+			"v": []interface{}{"paid", "new"},
+		},
+	},
+	{
+		source:    "html_body",
+		property:  "form input[name=csrf]",
+		attribute: "value",
+		variable:  "v",
+		valid:     true,
+		inCtx: &resource.ExecutionContext{
+			Variables:           make(map[string]interface{}),
+			CurrentResponse:     &http.Response{},
+			CurrentResponseBody: []byte(`<html><body><form><input name="csrf" value="token"></form></body></html>`),
+		},
+		outVars: map[string]interface{}{
+			"v": "token",
+		},
+	},
+	{
+		source:   "html_body",
+		property: "h1",
+		variable: "v",
+		valid:    false,
+		inCtx: &resource.ExecutionContext{
+			Variables:           make(map[string]interface{}),
+			CurrentResponse:     &http.Response{},
+			CurrentResponseBody: []byte(`<html><body><p>text</p></body></html>`),
+		},
+		outVars: map[string]interface{}{},
+	},
+	{
+		source:   "header",
+		property: "content-type",
+		variable: "v",
+		valid:    true,
+		inCtx: &resource.ExecutionContext{
+			Variables: make(map[string]interface{}),
+			CurrentResponse: &http.Response{
+				Header: http.Header{
+					"Content-Type": []string{"application/json; charset=utf-8"},
+				},
+			},
+		},
+		outVars: map[string]interface{}{
+			"v": "application/json; charset=utf-8",
+		},
+	},
+	{
+		source:    "header",
+		property:  "Content-Type",
+		attribute: "charset",
+		variable:  "v",
+		valid:     true,
+		inCtx: &resource.ExecutionContext{
+			Variables: make(map[string]interface{}),
+			CurrentResponse: &http.Response{
+				Header: http.Header{
+					"Content-Type": []string{"text/html; charset=ISO-8859-1"},
+				},
+			},
+		},
+		outVars: map[string]interface{}{
+			"v": "ISO-8859-1",
+		},
 	},
 }
 
@@ -230,18 +428,45 @@ var testCases = []validationTestCase{
 		valid:    true,
 	},
 	{
-		source:       "json_body",
-		variable:     "v",
-		property:     "data.test",
-		numeric_type: "float",
-		valid:        true,
+		source:    "cookie",
+		variable:  "v",
+		property:  "session",
+		attribute: "expires_in",
+		valid:     true,
+	},
+	{
+		source:    "cookie",
+		variable:  "v",
+		property:  "session",
+		attribute: "color",
+		valid:     false,
+	},
+	{
+		source:    "json_body",
+		variable:  "v",
+		property:  "data.test",
+		attribute: "value",
+		valid:     false,
+	},
+	{
+		source:    "header",
+		variable:  "v",
+		property:  "content-type",
+		attribute: "media_type",
+		valid:     true,
 	},
 	{
-		source:       "json_body",
-		variable:     "v",
-		property:     "data.test",
-		numeric_type: "invalid",
-		valid:        false,
+		source:    "html_body",
+		variable:  "v",
+		property:  "a.next",
+		attribute: "href",
+		valid:     true,
+	},
+	{
+		source:   "xml_body",
+		variable: "v",
+		property: "//order[",
+		valid:    false,
 	},
 	{
 		source:   "invalid_source",
@@ -269,10 +494,10 @@ func TestValidation(t *testing.T) {
 					Text: []byte("name"),
 				},
 			},
-			source:       testCase.source,
-			property:     testCase.property,
-			variable:     testCase.variable,
-			numeric_type: testCase.numeric_type,
+			source:    testCase.source,
+			property:  testCase.property,
+			attribute: testCase.attribute,
+			variable:  testCase.variable,
 		}
 
 		t.Logf("%v", testCase)
@@ -285,6 +510,16 @@ func TestValidation(t *testing.T) {
 	}
 }
 
+func TestNumericTypeIsRejected(t *testing.T) {
+	_, err := New(bcltest.Block(t, `resource "http_variable" "id" {
+		source = "json_body"
+		property = "id"
+		variable = "id"
+		numeric_type = "int"
+	}`))
+	assert.EqualError(t, err, "`numeric_type` is no longer supported, JSON numbers are captured as numbers, remove it")
+}
+
 func TestExec(t *testing.T) {
 	for _, testCase := range execTestCases {
 		r := &Resource{
@@ -297,10 +532,10 @@ func TestExec(t *testing.T) {
 					Text: []byte("name"),
 				},
 			},
-			source:       testCase.source,
-			property:     testCase.property,
-			variable:     testCase.variable,
-			numeric_type: testCase.numeric_type,
+			source:    testCase.source,
+			property:  testCase.property,
+			attribute: testCase.attribute,
+			variable:  testCase.variable,
 		}
 
 		t.Logf("%v", testCase)
@@ -314,3 +549,13 @@ func TestExec(t *testing.T) {
 		assert.Equal(t, testCase.outVars, testCase.inCtx.Variables)
 	}
 }
+
+func TestUnknownBlock(t *testing.T) {
+	_, err := New(bcltest.Block(t, `resource "http_variable" "a" {
+		source = "header"
+		property = "Location"
+		variable = "location"
+		auth "basic" { username = "a" }
+	}`))
+	assert.EqualError(t, err, `unknown block "auth"`)
+}
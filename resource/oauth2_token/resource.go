@@ -0,0 +1,408 @@
+package oauth2_token
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+
+	"github.com/bluebookrun/bluebook/bcl"
+	"github.com/bluebookrun/bluebook/interpolator"
+	"github.com/bluebookrun/bluebook/resource"
+	"github.com/bluebookrun/bluebook/resource/http_client"
+)
+
+// Grant types
+const (
+	GrantClientCredentials = "client_credentials"
+	GrantPassword          = "password"
+)
+
+// Client authentication methods
+const (
+	ClientAuthBasic = "basic" // client ID and secret in the Authorization header
+	ClientAuthBody  = "body"  // client ID and secret in the form body
+)
+
+// Attributes of the token, available as ${oauth2_token.name.attribute}
+var Attributes = []string{"access_token", "token_type", "refresh_token", "scope", "expires_in"}
+
+// replaced in tests
+var now = time.Now
+
+// Resource fetches an access token from an OAuth2 token endpoint when
+// it's referenced. Tokens are cached for all tests of the run, and
+// fetched again when they expire.
+type Resource struct {
+	Node          *bcl.BlockNode
+	TokenURL      string
+	GrantType     string
+	ClientID      string
+	ClientSecret  string
+	ClientAuth    string
+	Username      string
+	Password      string
+	Scope         string
+	Params        map[string]string // additional form fields, e.g. audience
+	RefreshBefore time.Duration     // tokens are refreshed this long before they expire
+	ClientOptions *http_client.Options
+
+	attributes map[string]string
+	mu         sync.Mutex
+	tokens     map[string]*token // by evaluated inputs
+	current    *token            // token of the most recent reference
+}
+
+type token struct {
+	AccessToken  string      `json:"access_token"`
+	TokenType    string      `json:"token_type"`
+	RefreshToken string      `json:"refresh_token"`
+	Scope        string      `json:"scope"`
+	ExpiresIn    json.Number `json:"expires_in"`
+
+	expires time.Time // zero if the token doesn't expire
+}
+
+// grant are evaluated inputs of a token request
+type grant struct {
+	tokenURL     string
+	clientID     string
+	clientSecret string
+	username     string
+	password     string
+	scope        string
+	params       map[string]string
+}
+
+func New(node *bcl.BlockNode) (*Resource, error) {
+	r := &Resource{
+		Node:          node,
+		ClientAuth:    ClientAuthBasic,
+		Params:        make(map[string]string),
+		RefreshBefore: 10 * time.Second,
+		ClientOptions: &http_client.Options{},
+		attributes: map[string]string{
+			"id": uuid.New().String(),
+		},
+		tokens: make(map[string]*token),
+	}
+
+	if len(node.Blocks) != 0 {
+		return nil, fmt.Errorf("unknown block %q", node.Blocks[0].Id.Text)
+	}
+
+	for _, expression := range node.Expressions {
+		if string(expression.Field.Text) == "params" {
+			mapNode, err := expression.ValueAsMap()
+			if err != nil {
+				return nil, err
+			}
+			for _, entry := range mapNode.Entries {
+				value, err := entry.ValueAsString()
+				if err != nil {
+					return nil, fmt.Errorf("`params` value of %q must be a string", entry.Field.Text)
+				}
+				r.Params[string(entry.Field.Text)] = value
+			}
+			continue
+		}
+
+		if ok, err := r.ClientOptions.Parse(expression); ok || err != nil {
+			if err != nil {
+				return nil, err
+			}
+			continue
+		}
+
+		value, err := expression.ValueAsString()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case string(expression.Field.Text) == "token_url":
+			r.TokenURL = value
+		case string(expression.Field.Text) == "grant_type":
+			if value != GrantClientCredentials && value != GrantPassword {
+				return nil, fmt.Errorf("invalid `grant_type` value %q, allowed values are %q and %q",
+					value, GrantClientCredentials, GrantPassword)
+			}
+			r.GrantType = value
+		case string(expression.Field.Text) == "client_id":
+			r.ClientID = value
+		case string(expression.Field.Text) == "client_secret":
+			r.ClientSecret = value
+		case string(expression.Field.Text) == "client_auth":
+			if value != ClientAuthBasic && value != ClientAuthBody {
+				return nil, fmt.Errorf("invalid `client_auth` value %q, allowed values are %q and %q",
+					value, ClientAuthBasic, ClientAuthBody)
+			}
+			r.ClientAuth = value
+		case string(expression.Field.Text) == "username":
+			r.Username = value
+		case string(expression.Field.Text) == "password":
+			r.Password = value
+		case string(expression.Field.Text) == "scope":
+			r.Scope = value
+		case string(expression.Field.Text) == "refresh_before":
+			d, err := time.ParseDuration(value)
+			if err != nil || d < 0 {
+				return nil, fmt.Errorf("invalid `refresh_before` value %q", value)
+			}
+			r.RefreshBefore = d
+		default:
+			return nil, fmt.Errorf("unknown oauth2_token input %q", expression.Field.Text)
+		}
+	}
+
+	if r.TokenURL == "" {
+		return nil, fmt.Errorf("`token_url` is required")
+	}
+
+	if r.GrantType == "" {
+		return nil, fmt.Errorf("`grant_type` is required")
+	}
+
+	if r.ClientID == "" {
+		return nil, fmt.Errorf("`client_id` is required")
+	}
+
+	if r.GrantType == GrantPassword && r.Username == "" {
+		return nil, fmt.Errorf("`username` is required for %q grant", GrantPassword)
+	}
+
+	if err := r.ClientOptions.Validate(); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+func (r *Resource) Link(ctx *resource.ExecutionContext) error {
+	return nil
+}
+
+// Exec fetches the token, tokens are usually fetched when referenced
+func (r *Resource) Exec(ctx *resource.ExecutionContext) error {
+	return r.Refresh(ctx)
+}
+
+func (r *Resource) GetAttribute(name string) *string {
+	if value, ok := r.attributes[name]; ok {
+		return &value
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.current == nil {
+		return nil
+	}
+
+	var value string
+	switch name {
+	case "access_token":
+		value = r.current.AccessToken
+	case "token_type":
+		value = r.current.TokenType
+	case "refresh_token":
+		value = r.current.RefreshToken
+	case "scope":
+		value = r.current.Scope
+	case "expires_in":
+		value = r.current.ExpiresIn.String()
+	default:
+		return nil
+	}
+	return &value
+}
+
+// Refresh makes sure that a valid token is cached. Expired tokens are
+// renewed with the refresh token, or with a new grant if the server
+// didn't issue one or refused it.
+func (r *Resource) Refresh(ctx *resource.ExecutionContext) error {
+	g, err := r.eval(ctx)
+	if err != nil {
+		return err
+	}
+	key := fmt.Sprintf("%#v", *g)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	cached := r.tokens[key]
+	if cached != nil && (cached.expires.IsZero() || now().Add(r.RefreshBefore).Before(cached.expires)) {
+		r.current = cached
+		return nil
+	}
+
+	client, err := ctx.Clients.Client(ctx.ClientOptions.Merge(r.ClientOptions), nil)
+	if err != nil {
+		return err
+	}
+
+	var t *token
+	if cached != nil && cached.RefreshToken != "" {
+		t, err = r.request(client, g, url.Values{
+			"grant_type":    {"refresh_token"},
+			"refresh_token": {cached.RefreshToken},
+		})
+		if t != nil && t.RefreshToken == "" {
+			// the refresh token can be used again
+			t.RefreshToken = cached.RefreshToken
+		}
+	}
+
+	if t == nil {
+		form := url.Values{"grant_type": {r.GrantType}}
+		if r.GrantType == GrantPassword {
+			form.Set("username", g.username)
+			form.Set("password", g.password)
+		}
+		t, err = r.request(client, g, form)
+	}
+
+	if err != nil {
+		return err
+	}
+
+	r.tokens[key] = t
+	r.current = t
+	return nil
+}
+
+func (r *Resource) eval(ctx *resource.ExecutionContext) (*grant, error) {
+	g := &grant{params: make(map[string]string)}
+
+	inputs := []struct {
+		text  string
+		value *string
+	}{
+		{r.TokenURL, &g.tokenURL},
+		{r.ClientID, &g.clientID},
+		{r.ClientSecret, &g.clientSecret},
+		{r.Username, &g.username},
+		{r.Password, &g.password},
+		{r.Scope, &g.scope},
+	}
+
+	for _, input := range inputs {
+		value, err := interpolator.Eval(input.text, ctx)
+		if err != nil {
+			return nil, err
+		}
+		*input.value = value
+	}
+
+	for name, text := range r.Params {
+		value, err := interpolator.Eval(text, ctx)
+		if err != nil {
+			return nil, err
+		}
+		g.params[name] = value
+	}
+
+	return g, nil
+}
+
+// request posts the form to the token endpoint
+func (r *Resource) request(client *http.Client, g *grant, form url.Values) (*token, error) {
+	if g.scope != "" {
+		form.Set("scope", g.scope)
+	}
+
+	for name, value := range g.params {
+		form.Set(name, value)
+	}
+
+	if r.ClientAuth == ClientAuthBody {
+		form.Set("client_id", g.clientID)
+		if g.clientSecret != "" {
+			form.Set("client_secret", g.clientSecret)
+		}
+	}
+
+	req, err := http.NewRequest("POST", g.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+
+	if r.ClientAuth == ClientAuthBasic {
+		req.SetBasicAuth(url.QueryEscape(g.clientID), url.QueryEscape(g.clientSecret))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("token request failed: %s", err.Error())
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read token response: %s", err.Error())
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		var e struct {
+			Error       string `json:"error"`
+			Description string `json:"error_description"`
+		}
+		if json.Unmarshal(body, &e) == nil && e.Error != "" {
+			if e.Description != "" {
+				return nil, fmt.Errorf("token request failed with status %d: %s: %s", resp.StatusCode, e.Error, e.Description)
+			}
+			return nil, fmt.Errorf("token request failed with status %d: %s", resp.StatusCode, e.Error)
+		}
+		if excerpt := bodyExcerpt(body); excerpt != "" {
+			return nil, fmt.Errorf("token request failed with status %d, response body %q", resp.StatusCode, excerpt)
+		}
+		return nil, fmt.Errorf("token request failed with status %d", resp.StatusCode)
+	}
+
+	t := &token{}
+	if err := json.Unmarshal(body, t); err != nil {
+		return nil, fmt.Errorf("invalid token response: %s", err.Error())
+	}
+
+	if t.AccessToken == "" {
+		return nil, fmt.Errorf("token response has no `access_token`")
+	}
+
+	if t.ExpiresIn != "" {
+		// some servers send floats, e.g. 3600.0
+		seconds, err := resource.ToFloat64(t.ExpiresIn)
+		if err != nil {
+			return nil, fmt.Errorf("invalid `expires_in` value %q", t.ExpiresIn)
+		}
+		t.expires = now().Add(time.Duration(seconds * float64(time.Second)))
+	}
+
+	return t, nil
+}
+
+// maxBodyExcerpt is how much of a failed token response is shown in errors
+const maxBodyExcerpt = 200
+
+// bodyExcerpt returns the beginning of body for error messages
+func bodyExcerpt(body []byte) string {
+	excerpt := strings.TrimSpace(string(body))
+	if len(excerpt) <= maxBodyExcerpt {
+		return excerpt
+	}
+
+	// don't cut a multi-byte character
+	end := maxBodyExcerpt
+	for end > 0 && !utf8.RuneStart(excerpt[end]) {
+		end--
+	}
+	return excerpt[:end] + "..."
+}
@@ -0,0 +1,237 @@
+package oauth2_token
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/bluebookrun/bluebook/bcl/bcltest"
+	"github.com/bluebookrun/bluebook/interpolator"
+	"github.com/bluebookrun/bluebook/resource"
+)
+
+// authServer issues tokens numbered by request, tokens expire in an hour
+type authServer struct {
+	*httptest.Server
+	requests []map[string]string // form fields and basic auth of token requests
+	fail     bool                // answer with invalid_grant
+}
+
+func newAuthServer() *authServer {
+	s := &authServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		request := map[string]string{}
+		for name := range r.PostForm {
+			request[name] = r.PostForm.Get(name)
+		}
+		if username, password, ok := r.BasicAuth(); ok {
+			request["basic"] = username + ":" + password
+		}
+		s.requests = append(s.requests, request)
+
+		w.Header().Set("Content-Type", "application/json")
+		if s.fail || request["grant_type"] == "refresh_token" && request["refresh_token"] != "refresh-1" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"error": "invalid_grant", "error_description": "bad credentials"}`))
+			return
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token":  fmt.Sprintf("token-%d", len(s.requests)),
+			"token_type":    "Bearer",
+			"expires_in":    3600,
+			"refresh_token": fmt.Sprintf("refresh-%d", len(s.requests)),
+		})
+	}))
+	return s
+}
+
+func TestValidation(t *testing.T) {
+	valid := []string{
+		`resource "oauth2_token" "api" { token_url = "/", grant_type = "client_credentials", client_id = "a" }`,
+		`resource "oauth2_token" "api" { token_url = "/", grant_type = "password", client_id = "a", username = "u", password = "p" }`,
+		`resource "oauth2_token" "api" { token_url = "/", grant_type = "client_credentials", client_id = "a", client_auth = "body", params = { audience = "api" }, timeout = "5s" }`,
+	}
+
+	for _, text := range valid {
+		_, err := New(bcltest.Block(t, text))
+		assert.Nil(t, err, text)
+	}
+
+	invalid := []string{
+		`resource "oauth2_token" "api" { grant_type = "client_credentials", client_id = "a" }`,
+		`resource "oauth2_token" "api" { token_url = "/", client_id = "a" }`,
+		`resource "oauth2_token" "api" { token_url = "/", grant_type = "implicit", client_id = "a" }`,
+		`resource "oauth2_token" "api" { token_url = "/", grant_type = "client_credentials" }`,
+		`resource "oauth2_token" "api" { token_url = "/", grant_type = "password", client_id = "a" }`,
+		`resource "oauth2_token" "api" { token_url = "/", grant_type = "client_credentials", client_id = "a", client_auth = "jwt" }`,
+		`resource "oauth2_token" "api" { token_url = "/", grant_type = "client_credentials", client_id = "a", refresh_before = "soon" }`,
+		`resource "oauth2_token" "api" { token_url = "/", grant_type = "client_credentials", client_id = "a", timeout = "soon" }`,
+		`resource "oauth2_token" "api" { token_url = "/", grant_type = "client_credentials", client_id = "a", unknown = "b" }`,
+		`resource "oauth2_token" "api" {
+			token_url = "/"
+			grant_type = "client_credentials"
+			client_id = "a"
+			auth "basic" { username = "a" }
+		}`,
+	}
+
+	for _, text := range invalid {
+		_, err := New(bcltest.Block(t, text))
+		assert.NotNil(t, err, text)
+	}
+}
+
+func TestClientCredentials(t *testing.T) {
+	server := newAuthServer()
+	defer server.Close()
+
+	clock := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	r, err := New(bcltest.Block(t, `resource "oauth2_token" "api" {
+		token_url = "`+server.URL+`/token"
+		grant_type = "client_credentials"
+		client_id = "client"
+		client_secret = "${var.secret}"
+		scope = "read write"
+	}`))
+	assert.Nil(t, err)
+
+	ctx := resource.NewExecutionContext()
+	ctx.SetVariable("secret", "s3cret")
+	assert.Nil(t, ctx.AddResource("oauth2_token.api", r))
+
+	// the token isn't fetched until it's referenced
+	assert.Nil(t, r.GetAttribute("access_token"))
+	assert.NotNil(t, r.GetAttribute("id"))
+
+	for i := 0; i < 3; i++ {
+		value, err := interpolator.Eval("${oauth2_token.api.access_token}", ctx)
+		assert.Nil(t, err)
+		assert.Equal(t, "token-1", value)
+	}
+
+	assert.Equal(t, []map[string]string{
+		{"grant_type": "client_credentials", "scope": "read write", "basic": "client:s3cret"},
+	}, server.requests)
+	assert.Equal(t, "Bearer", *r.GetAttribute("token_type"))
+	assert.Equal(t, "3600", *r.GetAttribute("expires_in"))
+
+	// tests of the run share the token
+	testCtx := ctx.Copy()
+	testCtx.SetVariable("secret", "s3cret")
+	value, err := interpolator.Eval("${oauth2_token.api.access_token}", testCtx)
+	assert.Nil(t, err)
+	assert.Equal(t, "token-1", value)
+	assert.Equal(t, 1, len(server.requests))
+
+	// tokens are refreshed before they expire
+	clock = clock.Add(3595 * time.Second)
+	value, err = interpolator.Eval("${oauth2_token.api.access_token}", ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "token-2", value)
+	assert.Equal(t, map[string]string{
+		"grant_type": "refresh_token", "refresh_token": "refresh-1", "scope": "read write", "basic": "client:s3cret",
+	}, server.requests[1])
+
+	// refused refresh falls back to a new grant
+	clock = clock.Add(time.Hour)
+	value, err = interpolator.Eval("${oauth2_token.api.access_token}", ctx)
+	assert.Nil(t, err)
+	assert.Equal(t, "token-4", value)
+	assert.Equal(t, "refresh_token", server.requests[2]["grant_type"])
+	assert.Equal(t, "client_credentials", server.requests[3]["grant_type"])
+}
+
+func TestPasswordGrant(t *testing.T) {
+	server := newAuthServer()
+	defer server.Close()
+
+	r, err := New(bcltest.Block(t, `resource "oauth2_token" "user" {
+		token_url = "`+server.URL+`/token"
+		grant_type = "password"
+		client_id = "client"
+		client_auth = "body"
+		username = "${var.username}"
+		password = "secret"
+		params = { audience = "https://api.example.com" }
+	}`))
+	assert.Nil(t, err)
+
+	ctx := resource.NewExecutionContext()
+	assert.Nil(t, ctx.AddResource("oauth2_token.user", r))
+
+	// every user gets its own token
+	for _, username := range []string{"alice", "bob", "alice"} {
+		ctx.SetVariable("username", username)
+		_, err := interpolator.Eval("${oauth2_token.user.access_token}", ctx)
+		assert.Nil(t, err)
+	}
+
+	assert.Equal(t, []map[string]string{
+		{"grant_type": "password", "username": "alice", "password": "secret", "client_id": "client", "audience": "https://api.example.com"},
+		{"grant_type": "password", "username": "bob", "password": "secret", "client_id": "client", "audience": "https://api.example.com"},
+	}, server.requests)
+	assert.Equal(t, "token-1", *r.GetAttribute("access_token"))
+
+	server.fail = true
+	ctx.SetVariable("username", "carol")
+	_, err = interpolator.Eval("${oauth2_token.user.access_token}", ctx)
+	assert.EqualError(t, err, "oauth2_token.user: token request failed with status 400: invalid_grant: bad credentials")
+}
+
+func TestTokenResponses(t *testing.T) {
+	var status int
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(status)
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	clock := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	now = func() time.Time { return clock }
+	defer func() { now = time.Now }()
+
+	cases := []struct {
+		status int
+		body   string
+		err    string
+	}{
+		{200, `{"access_token": "a", "expires_in": 3600.0}`, ""},
+		{502, "", "oauth2_token.api: token request failed with status 502"},
+		{502, "<html>Bad Gateway</html>\n", `oauth2_token.api: token request failed with status 502, response body "<html>Bad Gateway</html>"`},
+		{500, strings.Repeat("é", 150), `oauth2_token.api: token request failed with status 500, response body "` + strings.Repeat("é", 100) + `..."`},
+	}
+
+	for _, c := range cases {
+		status, body = c.status, c.body
+
+		r, err := New(bcltest.Block(t, `resource "oauth2_token" "api" {
+			token_url = "`+server.URL+`"
+			grant_type = "client_credentials"
+			client_id = "client"
+		}`))
+		assert.Nil(t, err)
+
+		ctx := resource.NewExecutionContext()
+		assert.Nil(t, ctx.AddResource("oauth2_token.api", r))
+
+		_, err = interpolator.Eval("${oauth2_token.api.access_token}", ctx)
+		if c.err == "" {
+			assert.Nil(t, err, c.body)
+			assert.Equal(t, clock.Add(time.Hour), r.current.expires)
+		} else {
+			assert.EqualError(t, err, c.err)
+		}
+	}
+}
@@ -2,21 +2,42 @@ package resource
 
 import (
 	"fmt"
+	"github.com/bluebookrun/bluebook/resource/http_auth"
+	"github.com/bluebookrun/bluebook/resource/http_client"
 	"net/http"
 )
 
 type ExecutionContext struct {
 	ReferenceToResourceMap map[string]Resource
 	IdToResourceMap        map[string]Resource
-	CurrentResponse        *http.Response // response from the most recent request
-	CurrentResponseBody    []byte         // response body of the most recent request
-	Variables              map[string]string
+	CurrentResponse        *http.Response         // response from the most recent request
+	CurrentResponseBody    []byte                 // response body of the most recent request
+	CurrentResult          *StepResult            // record of the most recent request
+	CurrentTest            string                 // reference of the running test, e.g. http_test.login
+	CurrentStep            string                 // reference of the running step
+	Variables              map[string]interface{} // strings or decoded JSON values
+	Locals                 map[string]interface{} // values of locals blocks, same for all tests
+	Seed                   int64                  // seed for random data generators
+	ClientOptions          *http_client.Options   // settings block and http_test client options
+	Clients                *http_client.Pool      // transports shared by all requests of the run
+	Cookies                string                 // default cookie mode of tests
+	CookieJar              http.CookieJar         // cookies of the running test, nil if disabled
+	Auth                   *http_auth.Auth        // authentication of the running test, nil if not set
+	UpdateSnapshots        bool                   // write snapshots instead of failing on differences
 }
 
 func (ctx *ExecutionContext) Copy() *ExecutionContext {
 	newCtx := NewExecutionContext()
 	newCtx.ReferenceToResourceMap = ctx.ReferenceToResourceMap
 	newCtx.IdToResourceMap = ctx.IdToResourceMap
+	newCtx.Locals = ctx.Locals
+	newCtx.Seed = ctx.Seed
+	newCtx.ClientOptions = ctx.ClientOptions
+	newCtx.Clients = ctx.Clients
+	newCtx.Cookies = ctx.Cookies
+	newCtx.CookieJar = ctx.CookieJar
+	newCtx.Auth = ctx.Auth
+	newCtx.UpdateSnapshots = ctx.UpdateSnapshots
 	return newCtx
 }
 
@@ -45,22 +66,32 @@ func (ctx *ExecutionContext) GetResourceByReference(ref string) Resource {
 	return nil
 }
 
-func (ctx *ExecutionContext) SetVariable(name string, value string) {
+func (ctx *ExecutionContext) SetVariable(name string, value interface{}) {
 	ctx.Variables[name] = value
 }
 
-func (ctx *ExecutionContext) GetVariable(name string) *string {
-	if value, ok := ctx.Variables[name]; ok {
-		return &value
-	}
-	return nil
+func (ctx *ExecutionContext) GetVariable(name string) (interface{}, bool) {
+	value, ok := ctx.Variables[name]
+	return value, ok
+}
+
+func (ctx *ExecutionContext) SetLocal(name string, value interface{}) {
+	ctx.Locals[name] = value
+}
+
+func (ctx *ExecutionContext) GetLocal(name string) (interface{}, bool) {
+	value, ok := ctx.Locals[name]
+	return value, ok
 }
 
 func NewExecutionContext() *ExecutionContext {
 	return &ExecutionContext{
 		ReferenceToResourceMap: make(map[string]Resource),
 		IdToResourceMap:        make(map[string]Resource),
-		Variables:              make(map[string]string),
+		Variables:              make(map[string]interface{}),
+		Locals:                 make(map[string]interface{}),
+		Clients:                http_client.NewPool(),
+		Cookies:                http_client.CookiesIsolated,
 	}
 }
 
@@ -69,3 +100,10 @@ type Resource interface {
 	Exec(*ExecutionContext) error
 	GetAttribute(string) *string
 }
+
+// Refresher is implemented by resources whose attributes are fetched when
+// they are referenced, e.g. OAuth2 tokens. Refresh is called before the
+// attributes are read.
+type Refresher interface {
+	Refresh(*ExecutionContext) error
+}
@@ -0,0 +1,74 @@
+package resource
+
+import (
+	"fmt"
+	"time"
+)
+
+// StepResult records how the most recent request was made, so that
+// assertions can check it and failures can report it.
+type StepResult struct {
+	Method   string
+	URL      string    // requested URL, including query parameters
+	Attempts []Attempt // one item per request, more if the request was retried
+	Polls    int       // number of requests made by wait_until
+
+	ContentEncoding string            // original encoding of the response body, e.g. gzip
+	BodySize        int64             // size of the decoded response body
+	BodyTruncated   bool              // whether the body is larger than max_body_size
+	BodyHashes      map[string]string // hex encoded md5, sha1 and sha256 of the decoded body
+
+	Timings Timings // timings of the request that produced the response
+}
+
+// Timings are durations of request phases. Phases that were skipped,
+// e.g. DNS lookup of a reused connection, are zero.
+type Timings struct {
+	DNS     time.Duration // DNS lookup
+	Connect time.Duration // TCP connection
+	TLS     time.Duration // TLS handshake
+	TTFB    time.Duration // from the start of the request to the first response byte
+	Total   time.Duration // from the start of the request to the end of the response body
+}
+
+// TimingPhases are names of Timings fields, see Phase
+var TimingPhases = []string{"dns", "connect", "tls", "ttfb", "total"}
+
+// Phase returns duration of the named phase
+func (t Timings) Phase(name string) (time.Duration, error) {
+	switch name {
+	case "dns":
+		return t.DNS, nil
+	case "connect":
+		return t.Connect, nil
+	case "tls":
+		return t.TLS, nil
+	case "ttfb":
+		return t.TTFB, nil
+	case "total":
+		return t.Total, nil
+	}
+	return 0, fmt.Errorf("unknown timing phase %q", name)
+}
+
+func (t Timings) String() string {
+	round := func(d time.Duration) time.Duration {
+		return d.Round(time.Microsecond)
+	}
+	return fmt.Sprintf("total %s (dns %s, connect %s, tls %s, ttfb %s)",
+		round(t.Total), round(t.DNS), round(t.Connect), round(t.TLS), round(t.TTFB))
+}
+
+// Attempt is a single try of a request
+type Attempt struct {
+	StatusCode int    // 0 if the request failed
+	Error      string // request error, e.g. connection refused
+	Duration   time.Duration
+}
+
+func (a Attempt) String() string {
+	if a.Error != "" {
+		return a.Error
+	}
+	return fmt.Sprintf("status %d", a.StatusCode)
+}
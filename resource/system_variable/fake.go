@@ -0,0 +1,126 @@
+package system_variable
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+)
+
+// fake data generators, values only depend on the random generator so
+// they are reproducible with the same seed
+var fakers = map[string]func(*rand.Rand) string{
+	"first_name":     fakeFirstName,
+	"last_name":      fakeLastName,
+	"name":           fakeName,
+	"email":          fakeEmail,
+	"username":       fakeUsername,
+	"phone":          fakePhone,
+	"street_address": fakeStreetAddress,
+	"city":           fakeCity,
+	"zip":            fakeZip,
+	"country":        fakeCountry,
+	"address":        fakeAddress,
+	"company":        fakeCompany,
+}
+
+var firstNames = []string{
+	"Alice", "Bob", "Carol", "David", "Emma", "Frank", "Grace", "Henry",
+	"Isabel", "Jack", "Karen", "Liam", "Maria", "Noah", "Olivia", "Peter",
+	"Quinn", "Rachel", "Samuel", "Tara", "Umar", "Victoria", "William", "Zoe",
+}
+
+var lastNames = []string{
+	"Anderson", "Brown", "Clark", "Davis", "Evans", "Garcia", "Harris",
+	"Johnson", "King", "Lewis", "Martin", "Nguyen", "Owens", "Patel",
+	"Robinson", "Smith", "Taylor", "Walker", "Wilson", "Young",
+}
+
+var streetNames = []string{
+	"Oak", "Maple", "Cedar", "Pine", "Elm", "Washington", "Lake", "Hill",
+	"Park", "Main", "Church", "Sunset", "Highland", "River", "Forest",
+}
+
+var streetSuffixes = []string{
+	"Street", "Avenue", "Road", "Lane", "Drive", "Court", "Boulevard", "Way",
+}
+
+var cities = []string{
+	"Springfield", "Riverside", "Fairview", "Franklin", "Greenville",
+	"Bristol", "Clinton", "Georgetown", "Salem", "Madison", "Oakland",
+}
+
+var countries = []string{
+	"United States", "Canada", "United Kingdom", "Germany", "France",
+	"Spain", "Italy", "Netherlands", "Sweden", "Australia", "Japan",
+}
+
+var companySuffixes = []string{
+	"Inc", "LLC", "Ltd", "Group", "Partners", "Labs", "Systems",
+}
+
+// reserved domains that never receive email
+var emailDomains = []string{
+	"example.com", "example.org", "example.net",
+}
+
+func pick(rnd *rand.Rand, values []string) string {
+	return values[rnd.Intn(len(values))]
+}
+
+func fakeFirstName(rnd *rand.Rand) string {
+	return pick(rnd, firstNames)
+}
+
+func fakeLastName(rnd *rand.Rand) string {
+	return pick(rnd, lastNames)
+}
+
+func fakeName(rnd *rand.Rand) string {
+	return fakeFirstName(rnd) + " " + fakeLastName(rnd)
+}
+
+func fakeUsername(rnd *rand.Rand) string {
+	return fmt.Sprintf("%s.%s%d",
+		strings.ToLower(fakeFirstName(rnd)),
+		strings.ToLower(fakeLastName(rnd)),
+		rnd.Intn(1000))
+}
+
+func fakeEmail(rnd *rand.Rand) string {
+	return fakeUsername(rnd) + "@" + pick(rnd, emailDomains)
+}
+
+func fakePhone(rnd *rand.Rand) string {
+	// 555-01xx numbers are reserved for fictional use
+	return fmt.Sprintf("+1-%03d-555-01%02d", 200+rnd.Intn(800), rnd.Intn(100))
+}
+
+func fakeStreetAddress(rnd *rand.Rand) string {
+	return fmt.Sprintf("%d %s %s",
+		1+rnd.Intn(9999),
+		pick(rnd, streetNames),
+		pick(rnd, streetSuffixes))
+}
+
+func fakeCity(rnd *rand.Rand) string {
+	return pick(rnd, cities)
+}
+
+func fakeZip(rnd *rand.Rand) string {
+	return fmt.Sprintf("%05d", rnd.Intn(100000))
+}
+
+func fakeCountry(rnd *rand.Rand) string {
+	return pick(rnd, countries)
+}
+
+func fakeAddress(rnd *rand.Rand) string {
+	return fmt.Sprintf("%s, %s %s",
+		fakeStreetAddress(rnd),
+		fakeCity(rnd),
+		fakeZip(rnd))
+}
+
+func fakeCompany(rnd *rand.Rand) string {
+	return fakeLastName(rnd) + " " + pick(rnd, companySuffixes)
+}
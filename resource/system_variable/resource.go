@@ -2,6 +2,11 @@ package system_variable
 
 import (
 	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"os"
+	"strconv"
 	"time"
 
 	"github.com/google/uuid"
@@ -11,12 +16,42 @@ import (
 	"github.com/bluebookrun/bluebook/resource"
 )
 
+// named character sets for random_string source, any other
+// charset value is used as a list of characters
+var Charsets = map[string]string{
+	"alphanumeric": "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789",
+	"alpha":        "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"lower":        "abcdefghijklmnopqrstuvwxyz",
+	"upper":        "ABCDEFGHIJKLMNOPQRSTUVWXYZ",
+	"numeric":      "0123456789",
+	"hex":          "0123456789abcdef",
+}
+
 type Resource struct {
 	Node       *bcl.BlockNode
 	attributes map[string]string
 	source     string
 	variable   string
 	format     string
+	offset     string
+	property   string
+	min        string
+	max        string
+	length     string
+	charset    string
+	start      string
+	step       string
+
+	// parsed inputs
+	offsetDuration time.Duration
+	minInt         int64
+	maxInt         int64
+	lengthInt      int
+	startInt       int64
+	stepInt        int64
+
+	rand    *rand.Rand // created on first use from the execution seed
+	counter int64      // next value of the sequence
 }
 
 func New(node *bcl.BlockNode) (*Resource, error) {
@@ -27,6 +62,10 @@ func New(node *bcl.BlockNode) (*Resource, error) {
 		},
 	}
 
+	if len(node.Blocks) != 0 {
+		return nil, fmt.Errorf("unknown block %q", node.Blocks[0].Id.Text)
+	}
+
 	for _, expression := range node.Expressions {
 		switch {
 		case string(expression.Field.Text) == "source":
@@ -47,13 +86,57 @@ func New(node *bcl.BlockNode) (*Resource, error) {
 				return nil, err
 			}
 			r.format = value
+		case string(expression.Field.Text) == "offset":
+			value, err := expression.ValueAsString()
+			if err != nil {
+				return nil, err
+			}
+			r.offset = value
+		case string(expression.Field.Text) == "property":
+			value, err := expression.ValueAsString()
+			if err != nil {
+				return nil, err
+			}
+			r.property = value
+		case string(expression.Field.Text) == "min":
+			value, err := expression.ValueAsString()
+			if err != nil {
+				return nil, err
+			}
+			r.min = value
+		case string(expression.Field.Text) == "max":
+			value, err := expression.ValueAsString()
+			if err != nil {
+				return nil, err
+			}
+			r.max = value
+		case string(expression.Field.Text) == "length":
+			value, err := expression.ValueAsString()
+			if err != nil {
+				return nil, err
+			}
+			r.length = value
+		case string(expression.Field.Text) == "charset":
+			value, err := expression.ValueAsString()
+			if err != nil {
+				return nil, err
+			}
+			r.charset = value
+		case string(expression.Field.Text) == "start":
+			value, err := expression.ValueAsString()
+			if err != nil {
+				return nil, err
+			}
+			r.start = value
+		case string(expression.Field.Text) == "step":
+			value, err := expression.ValueAsString()
+			if err != nil {
+				return nil, err
+			}
+			r.step = value
 		}
 	}
 
-	if r.format == "" {
-		r.format = "unixnano"
-	}
-
 	if err := validateResource(r); err != nil {
 		return nil, err
 	}
@@ -70,33 +153,126 @@ func validateResource(r *Resource) error {
 		return fmt.Errorf("`variable` is required")
 	}
 
-	if r.source != "time" {
-		return fmt.Errorf("invalid `source` value")
-	} else {
+	switch r.source {
+	case "time":
 		return validateTime(r)
+	case "uuid":
+		return nil
+	case "random_int":
+		return validateRandomInt(r)
+	case "random_string":
+		return validateRandomString(r)
+	case "sequence":
+		return validateSequence(r)
+	case "env":
+		if r.property == "" {
+			return fmt.Errorf("`property` is required for source %q", r.source)
+		}
+		return nil
+	case "fake":
+		if _, ok := fakers[r.property]; !ok {
+			return fmt.Errorf("invalid `property` value %q for source %q", r.property, r.source)
+		}
+		return nil
 	}
 
-	return nil
+	return fmt.Errorf("invalid `source` value")
 }
 
 func validateTime(r *Resource) error {
-	allowedFormats := []string{"unixnano", "unix", "rfc3339"}
+	// named formats, any other value is used as a Go time layout,
+	// e.g. 2006-01-02
+	if r.format == "" {
+		r.format = "unixnano"
+	}
 
-	found := false
-	for i := range allowedFormats {
-		if r.format == allowedFormats[i] {
-			found = true
-			break
+	if r.offset != "" {
+		d, err := time.ParseDuration(r.offset)
+		if err != nil {
+			return fmt.Errorf("invalid `offset` value: %s", err.Error())
 		}
+		r.offsetDuration = d
 	}
 
-	if !found {
-		return fmt.Errorf("invalid `format` value for source %q", r.source)
+	return nil
+}
+
+func validateRandomInt(r *Resource) error {
+	var err error
+
+	r.minInt, err = parseInt("min", r.min, 0)
+	if err != nil {
+		return err
+	}
+
+	r.maxInt, err = parseInt("max", r.max, math.MaxInt32)
+	if err != nil {
+		return err
+	}
+
+	if r.minInt > r.maxInt {
+		return fmt.Errorf("`min` must not be greater than `max`")
+	}
+
+	// max - min + 1 must fit into int64
+	if r.maxInt-r.minInt < 0 || r.maxInt-r.minInt == math.MaxInt64 {
+		return fmt.Errorf("range between `min` and `max` is too large")
+	}
+
+	return nil
+}
+
+func validateRandomString(r *Resource) error {
+	length, err := parseInt("length", r.length, 16)
+	if err != nil {
+		return err
+	}
+
+	if length <= 0 {
+		return fmt.Errorf("`length` must be positive")
+	}
+	r.lengthInt = int(length)
+
+	if r.charset == "" {
+		r.charset = "alphanumeric"
 	}
 
 	return nil
 }
 
+func validateSequence(r *Resource) error {
+	var err error
+
+	r.startInt, err = parseInt("start", r.start, 1)
+	if err != nil {
+		return err
+	}
+
+	r.stepInt, err = parseInt("step", r.step, 1)
+	if err != nil {
+		return err
+	}
+
+	if r.stepInt <= 0 {
+		return fmt.Errorf("`step` must be positive")
+	}
+
+	r.counter = r.startInt
+	return nil
+}
+
+func parseInt(field string, value string, defaultValue int64) (int64, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+
+	i, err := strconv.ParseInt(value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid `%s` value %q", field, value)
+	}
+	return i, nil
+}
+
 func (r *Resource) Link(ctx *resource.ExecutionContext) error {
 	return nil
 }
@@ -120,18 +296,85 @@ func (r *Resource) Exec(ctx *resource.ExecutionContext) error {
 		return nil
 	}
 
-	var value string
-	nowUtc := time.Now().UTC()
+	value, err := r.generate(ctx)
+	if err != nil {
+		return fmt.Errorf("%s: %s", r.Node.Ref(), err.Error())
+	}
+
+	ctx.SetVariable(variable, value)
+	return nil
+}
+
+func (r *Resource) generate(ctx *resource.ExecutionContext) (interface{}, error) {
+	switch r.source {
+	case "time":
+		return r.generateTime(), nil
+	case "uuid":
+		id, err := uuid.NewRandomFromReader(r.random(ctx))
+		if err != nil {
+			return nil, err
+		}
+		return id.String(), nil
+	case "random_int":
+		return r.minInt + r.random(ctx).Int63n(r.maxInt-r.minInt+1), nil
+	case "random_string":
+		return r.generateString(ctx), nil
+	case "sequence":
+		value := r.counter
+		r.counter += r.stepInt
+		return value, nil
+	case "env":
+		value, ok := os.LookupEnv(r.property)
+		if !ok {
+			return nil, fmt.Errorf("environment variable %q is not set", r.property)
+		}
+		return value, nil
+	case "fake":
+		return fakers[r.property](r.random(ctx)), nil
+	}
+
+	return nil, fmt.Errorf("unsupported source %q", r.source)
+}
+
+func (r *Resource) generateTime() interface{} {
+	t := time.Now().UTC().Add(r.offsetDuration)
 
 	switch r.format {
 	case "unixnano":
-		value = fmt.Sprintf("%d", nowUtc.UnixNano())
+		return t.UnixNano()
+	case "unixmilli":
+		return t.UnixNano() / int64(time.Millisecond)
 	case "unix":
-		value = fmt.Sprintf("%d", nowUtc.Unix())
+		return t.Unix()
 	case "rfc3339":
-		value = nowUtc.Format(time.RFC3339)
+		return t.Format(time.RFC3339)
 	}
+	return t.Format(r.format)
+}
 
-	ctx.SetVariable(variable, value)
-	return nil
+func (r *Resource) generateString(ctx *resource.ExecutionContext) string {
+	charset, ok := Charsets[r.charset]
+	if !ok {
+		charset = r.charset
+	}
+
+	chars := []rune(charset)
+	rnd := r.random(ctx)
+
+	value := make([]rune, r.lengthInt)
+	for i := range value {
+		value[i] = chars[rnd.Intn(len(chars))]
+	}
+	return string(value)
+}
+
+// random returns generator seeded with execution seed and resource
+// reference, so that generated values don't depend on other resources
+func (r *Resource) random(ctx *resource.ExecutionContext) *rand.Rand {
+	if r.rand == nil {
+		h := fnv.New64a()
+		h.Write([]byte(r.Node.Ref()))
+		r.rand = rand.New(rand.NewSource(ctx.Seed ^ int64(h.Sum64())))
+	}
+	return r.rand
 }
@@ -0,0 +1,178 @@
+package system_variable
+
+import (
+	"github.com/bluebookrun/bluebook/bcl"
+	"github.com/bluebookrun/bluebook/bcl/bcltest"
+	"github.com/bluebookrun/bluebook/resource"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"os"
+	"regexp"
+	"testing"
+	"time"
+)
+
+type validationTestCase struct {
+	source   string
+	format   string
+	offset   string
+	property string
+	min      string
+	max      string
+	length   string
+	start    string
+	step     string
+	valid    bool
+}
+
+var validationTestCases = []validationTestCase{
+	{source: "", valid: false},
+	{source: "invalid", valid: false},
+	{source: "time", valid: true},
+	{source: "time", format: "2006-01-02", offset: "-24h", valid: true},
+	{source: "time", offset: "tomorrow", valid: false},
+	{source: "uuid", valid: true},
+	{source: "random_int", min: "5", max: "10", valid: true},
+	{source: "random_int", min: "10", max: "5", valid: false},
+	{source: "random_int", min: "a", valid: false},
+	{source: "random_int", min: "-9223372036854775808", max: "9223372036854775807", valid: false},
+	{source: "random_string", length: "8", valid: true},
+	{source: "random_string", length: "0", valid: false},
+	{source: "sequence", start: "100", step: "10", valid: true},
+	{source: "sequence", step: "0", valid: false},
+	{source: "env", valid: false},
+	{source: "env", property: "HOME", valid: true},
+	{source: "fake", property: "email", valid: true},
+	{source: "fake", property: "invalid", valid: false},
+}
+
+func newResource(c validationTestCase) *Resource {
+	return &Resource{
+		Node: &bcl.BlockNode{
+			Driver: &bcl.StringNode{
+				Text: []byte("system_variable"),
+			},
+			Name: &bcl.StringNode{
+				Text: []byte("name"),
+			},
+		},
+		source:   c.source,
+		variable: "v",
+		format:   c.format,
+		offset:   c.offset,
+		property: c.property,
+		min:      c.min,
+		max:      c.max,
+		length:   c.length,
+		start:    c.start,
+		step:     c.step,
+	}
+}
+
+func exec(t *testing.T, r *Resource, seed int64) interface{} {
+	ctx := resource.NewExecutionContext()
+	ctx.Seed = seed
+	assert.Nil(t, r.Exec(ctx))
+	value, _ := ctx.GetVariable("v")
+	return value
+}
+
+func TestValidation(t *testing.T) {
+	for _, c := range validationTestCases {
+		t.Logf("%v", c)
+		err := validateResource(newResource(c))
+		if c.valid {
+			assert.Nil(t, err)
+		} else {
+			assert.NotNil(t, err)
+		}
+	}
+}
+
+func TestTime(t *testing.T) {
+	r := newResource(validationTestCase{source: "time", format: "2006-01-02", offset: "24h"})
+	assert.Nil(t, validateResource(r))
+	assert.Equal(t, time.Now().UTC().Add(24*time.Hour).Format("2006-01-02"), exec(t, r, 1))
+
+	r = newResource(validationTestCase{source: "time", format: "unix"})
+	assert.Nil(t, validateResource(r))
+	assert.IsType(t, int64(0), exec(t, r, 1))
+}
+
+func TestRandomValues(t *testing.T) {
+	r := newResource(validationTestCase{source: "random_int", min: "5", max: "7"})
+	assert.Nil(t, validateResource(r))
+	for i := 0; i < 20; i++ {
+		value := exec(t, r, 1).(int64)
+		assert.True(t, value >= 5 && value <= 7)
+	}
+
+	r = newResource(validationTestCase{source: "random_string", length: "12"})
+	r.charset = "hex"
+	assert.Nil(t, validateResource(r))
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f]{12}$`), exec(t, r, 1))
+
+	r = newResource(validationTestCase{source: "uuid"})
+	assert.Nil(t, validateResource(r))
+	assert.Regexp(t, regexp.MustCompile(`^[0-9a-f-]{36}$`), exec(t, r, 1))
+}
+
+func TestSequence(t *testing.T) {
+	r := newResource(validationTestCase{source: "sequence", start: "100", step: "10"})
+	assert.Nil(t, validateResource(r))
+	assert.Equal(t, int64(100), exec(t, r, 1))
+	assert.Equal(t, int64(110), exec(t, r, 1))
+	assert.Equal(t, int64(120), exec(t, r, 1))
+}
+
+func TestEnv(t *testing.T) {
+	os.Setenv("BLUEBOOK_TEST_ENV", "value")
+	defer os.Unsetenv("BLUEBOOK_TEST_ENV")
+
+	r := newResource(validationTestCase{source: "env", property: "BLUEBOOK_TEST_ENV"})
+	assert.Nil(t, validateResource(r))
+	assert.Equal(t, "value", exec(t, r, 1))
+
+	r = newResource(validationTestCase{source: "env", property: "BLUEBOOK_TEST_UNDEFINED"})
+	assert.NotNil(t, r.Exec(resource.NewExecutionContext()))
+}
+
+func TestSeededValuesAreReproducible(t *testing.T) {
+	for name := range fakers {
+		generate := func(seed int64) []interface{} {
+			r := newResource(validationTestCase{source: "fake", property: name})
+			assert.Nil(t, validateResource(r))
+			return []interface{}{exec(t, r, seed), exec(t, r, seed)}
+		}
+
+		values := generate(42)
+		assert.Equal(t, values, generate(42), name)
+		assert.NotEmpty(t, values[0], name)
+	}
+
+	r := newResource(validationTestCase{source: "random_int"})
+	assert.Nil(t, validateResource(r))
+	first := exec(t, r, 7)
+
+	r = newResource(validationTestCase{source: "random_int"})
+	assert.Nil(t, validateResource(r))
+	assert.Equal(t, first, exec(t, r, 7))
+}
+
+func TestSkipsAfterRequest(t *testing.T) {
+	r := newResource(validationTestCase{source: "uuid"})
+	ctx := resource.NewExecutionContext()
+	ctx.CurrentResponse = &http.Response{}
+	assert.Nil(t, r.Exec(ctx))
+	_, ok := ctx.GetVariable("v")
+	assert.False(t, ok)
+}
+
+func TestUnknownBlock(t *testing.T) {
+	_, err := New(bcltest.Block(t, `resource "system_variable" "a" {
+		source = "uuid"
+		variable = "id"
+		auth "basic" { username = "a" }
+	}`))
+	assert.EqualError(t, err, `unknown block "auth"`)
+}
@@ -0,0 +1,232 @@
+package resource
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+)
+
+// Variable values are one of the following types:
+//
+//   string
+//   int64
+//   float64
+//   bool
+//   []interface{}          (list)
+//   map[string]interface{} (object)
+//   json.Number            (number that doesn't fit int64 or float64)
+//   json.RawMessage        (raw JSON document)
+//   nil                    (JSON null)
+//
+// All conversions between value types are defined in this file.
+
+// DecodeJSON decodes JSON document into a variable value. Integers are
+// decoded as int64 so large IDs don't lose precision.
+func DecodeJSON(data []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+
+	if decoder.More() {
+		return nil, fmt.Errorf("unexpected data after JSON document")
+	}
+
+	return NormalizeJSON(value), nil
+}
+
+// NormalizeJSON replaces json.Number values in a decoded JSON document with
+// int64 or float64 values.
+func NormalizeJSON(value interface{}) interface{} {
+	switch value := value.(type) {
+	case json.Number:
+		return normalizeNumber(value)
+	case []interface{}:
+		for i := range value {
+			value[i] = NormalizeJSON(value[i])
+		}
+	case map[string]interface{}:
+		for key := range value {
+			value[key] = NormalizeJSON(value[key])
+		}
+	}
+	return value
+}
+
+func normalizeNumber(n json.Number) interface{} {
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+
+	f, err := n.Float64()
+	if err != nil || math.IsInf(f, 0) {
+		// keep the original representation
+		return n
+	}
+
+	if isInteger(string(n)) {
+		// integers outside of int64 range would lose precision
+		return n
+	}
+	return f
+}
+
+func isInteger(s string) bool {
+	for i, c := range s {
+		if c == '-' && i == 0 {
+			continue
+		}
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ToString converts value to its string representation.
+// Lists and objects are formatted as JSON documents.
+func ToString(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "", nil
+	case string:
+		return value, nil
+	case bool:
+		return strconv.FormatBool(value), nil
+	case int64:
+		return strconv.FormatInt(value, 10), nil
+	case int:
+		return strconv.Itoa(value), nil
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64), nil
+	case json.Number:
+		return value.String(), nil
+	case json.RawMessage:
+		return string(value), nil
+	case []interface{}, map[string]interface{}:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+	return "", fmt.Errorf("unsupported value type %T", value)
+}
+
+// ToInt64 converts value to an integer. Strings are parsed, floats
+// are accepted only when they don't have a fractional part.
+func ToInt64(value interface{}) (int64, error) {
+	switch value := value.(type) {
+	case int64:
+		return value, nil
+	case int:
+		return int64(value), nil
+	case float64:
+		// float64 can't represent math.MaxInt64, 2^63 is the first float out of range
+		if value != math.Trunc(value) || value >= -math.MinInt64 || value < math.MinInt64 {
+			return 0, fmt.Errorf("%v is not an integer", value)
+		}
+		return int64(value), nil
+	case json.Number:
+		return value.Int64()
+	case string:
+		return strconv.ParseInt(value, 10, 64)
+	}
+	return 0, fmt.Errorf("%s is not a number", TypeName(value))
+}
+
+// ToFloat64 converts value to a floating point number.
+func ToFloat64(value interface{}) (float64, error) {
+	switch value := value.(type) {
+	case float64:
+		return value, nil
+	case int64:
+		return float64(value), nil
+	case int:
+		return float64(value), nil
+	case json.Number:
+		return value.Float64()
+	case string:
+		return strconv.ParseFloat(value, 64)
+	}
+	return 0, fmt.Errorf("%s is not a number", TypeName(value))
+}
+
+// ToBool converts value to a boolean, strings are parsed.
+func ToBool(value interface{}) (bool, error) {
+	switch value := value.(type) {
+	case bool:
+		return value, nil
+	case string:
+		return strconv.ParseBool(value)
+	}
+	return false, fmt.Errorf("%s is not a boolean", TypeName(value))
+}
+
+// ToList converts value to a list, strings are decoded as JSON documents.
+func ToList(value interface{}) ([]interface{}, error) {
+	if s, ok := value.(string); ok {
+		decoded, err := DecodeJSON([]byte(s))
+		if err != nil {
+			return nil, fmt.Errorf("string is not a JSON list")
+		}
+		value = decoded
+	}
+
+	if l, ok := value.([]interface{}); ok {
+		return l, nil
+	}
+	return nil, fmt.Errorf("%s is not a list", TypeName(value))
+}
+
+// ToDocument converts value to a JSON document that can be queried with
+// JSON path. Strings and raw JSON values are decoded.
+func ToDocument(value interface{}) (interface{}, error) {
+	switch value := value.(type) {
+	case string:
+		document, err := DecodeJSON([]byte(value))
+		if err != nil {
+			return nil, fmt.Errorf("value is not a JSON document")
+		}
+		return document, nil
+	case json.RawMessage:
+		return DecodeJSON(value)
+	}
+	return value, nil
+}
+
+// IsNumber reports whether value holds a number.
+func IsNumber(value interface{}) bool {
+	switch value.(type) {
+	case int64, int, float64, json.Number:
+		return true
+	}
+	return false
+}
+
+// TypeName returns JSON type name of value for error messages, e.g.
+// string or list.
+func TypeName(value interface{}) string {
+	switch value.(type) {
+	case nil:
+		return "null"
+	case string:
+		return "string"
+	case bool:
+		return "boolean"
+	case int64, int, float64, json.Number:
+		return "number"
+	case []interface{}:
+		return "list"
+	case map[string]interface{}:
+		return "object"
+	case json.RawMessage:
+		return "raw JSON"
+	}
+	return fmt.Sprintf("%T", value)
+}
@@ -0,0 +1,104 @@
+package resource
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"math"
+	"testing"
+)
+
+func TestDecodeJSON(t *testing.T) {
+	value, err := DecodeJSON([]byte(`{"id": 9007199254740993, "price": 1.5, "big": 100000000000000000000, "list": [1, "a", true, null]}`))
+	assert.Nil(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"id":    int64(9007199254740993),
+		"price": 1.5,
+		"big":   json.Number("100000000000000000000"),
+		"list":  []interface{}{int64(1), "a", true, nil},
+	}, value)
+
+	_, err = DecodeJSON([]byte(`{} {}`))
+	assert.NotNil(t, err)
+}
+
+func TestToString(t *testing.T) {
+	cases := []struct {
+		value    interface{}
+		expected string
+	}{
+		{nil, ""},
+		{"a", "a"},
+		{true, "true"},
+		{int64(9007199254740993), "9007199254740993"},
+		{1.5, "1.5"},
+		{123.0, "123"},
+		{json.Number("100000000000000000000"), "100000000000000000000"},
+		{json.RawMessage(`{"a":1}`), `{"a":1}`},
+		{[]interface{}{int64(1), "a"}, `[1,"a"]`},
+		{map[string]interface{}{"a": int64(1)}, `{"a":1}`},
+	}
+
+	for _, c := range cases {
+		value, err := ToString(c.value)
+		assert.Nil(t, err)
+		assert.Equal(t, c.expected, value)
+	}
+}
+
+func TestNumericConversions(t *testing.T) {
+	i, err := ToInt64("9007199254740993")
+	assert.Nil(t, err)
+	assert.Equal(t, int64(9007199254740993), i)
+
+	i, err = ToInt64(12.0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(12), i)
+
+	_, err = ToInt64(12.5)
+	assert.NotNil(t, err)
+
+	_, err = ToInt64(9223372036854775808.0)
+	assert.NotNil(t, err)
+
+	_, err = ToInt64(json.Number("9223372036854775808"))
+	assert.NotNil(t, err)
+
+	i, err = ToInt64(-9223372036854775808.0)
+	assert.Nil(t, err)
+	assert.Equal(t, int64(math.MinInt64), i)
+
+	_, err = ToInt64(true)
+	assert.NotNil(t, err)
+
+	f, err := ToFloat64(int64(3))
+	assert.Nil(t, err)
+	assert.Equal(t, 3.0, f)
+
+	f, err = ToFloat64("1.25")
+	assert.Nil(t, err)
+	assert.Equal(t, 1.25, f)
+
+	_, err = ToFloat64([]interface{}{})
+	assert.NotNil(t, err)
+}
+
+func TestToBool(t *testing.T) {
+	b, err := ToBool("true")
+	assert.Nil(t, err)
+	assert.True(t, b)
+
+	_, err = ToBool(int64(1))
+	assert.NotNil(t, err)
+}
+
+func TestToList(t *testing.T) {
+	l, err := ToList(`[1, 2]`)
+	assert.Nil(t, err)
+	assert.Equal(t, []interface{}{int64(1), int64(2)}, l)
+
+	_, err = ToList("a")
+	assert.NotNil(t, err)
+
+	_, err = ToList(map[string]interface{}{})
+	assert.NotNil(t, err)
+}